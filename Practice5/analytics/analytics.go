@@ -0,0 +1,179 @@
+// Package analytics computes community-health figures for the friendship
+// graph: connected components, degrees, local clustering coefficients, the
+// most connected and the isolated users. Everything runs in memory over a
+// models.FriendGraph snapshot.
+package analytics
+
+import (
+	"sort"
+	"time"
+
+	"practice5/models"
+)
+
+// UserStats describes one user's place in the graph.
+type UserStats struct {
+	ID     int `json:"id"`
+	Degree int `json:"degree"`
+	// Clustering is the share of pairs of the user's friends who are
+	// friends themselves; 0 with fewer than two friends.
+	Clustering float64 `json:"clustering"`
+	Component  int     `json:"component"`
+}
+
+type Component struct {
+	ID      int   `json:"id"`
+	Size    int   `json:"size"`
+	UserIDs []int `json:"user_ids"`
+}
+
+type Summary struct {
+	ComputedAt        time.Time `json:"computed_at"`
+	Users             int       `json:"users"`
+	Friendships       int       `json:"friendships"`
+	Components        int       `json:"components"`
+	LargestComponent  int       `json:"largest_component"`
+	Isolated          int       `json:"isolated"`
+	AverageDegree     float64   `json:"average_degree"`
+	AverageClustering float64   `json:"average_clustering"`
+}
+
+// Report holds the figures for one snapshot. It is read-only once built.
+type Report struct {
+	Summary    Summary
+	Components []Component // largest first, then by lowest user id
+	users      map[int]*UserStats
+	byDegree   []*UserStats // highest degree first, ties by id
+	isolated   []int
+}
+
+// Analyze builds the report for g. Friendships with a user missing from
+// g.UserIDs are ignored.
+func Analyze(g models.FriendGraph, now time.Time) *Report {
+	adj := make(map[int]map[int]bool, len(g.UserIDs))
+	for _, id := range g.UserIDs {
+		adj[id] = map[int]bool{}
+	}
+	friendships := 0
+	for _, e := range g.Edges {
+		a, b := e[0], e[1]
+		if a == b || adj[a] == nil || adj[b] == nil || adj[a][b] {
+			continue
+		}
+		adj[a][b], adj[b][a] = true, true
+		friendships++
+	}
+
+	ids := append([]int(nil), g.UserIDs...)
+	sort.Ints(ids)
+	rep := &Report{users: make(map[int]*UserStats, len(ids))}
+	var clusteringSum float64
+	for _, id := range ids {
+		s := &UserStats{ID: id, Degree: len(adj[id]), Clustering: clustering(adj, id)}
+		rep.users[id] = s
+		rep.byDegree = append(rep.byDegree, s)
+		clusteringSum += s.Clustering
+		if s.Degree == 0 {
+			rep.isolated = append(rep.isolated, id)
+		}
+	}
+	sort.SliceStable(rep.byDegree, func(i, j int) bool { return rep.byDegree[i].Degree > rep.byDegree[j].Degree })
+
+	rep.Components = components(adj, ids)
+	for _, c := range rep.Components {
+		for _, id := range c.UserIDs {
+			rep.users[id].Component = c.ID
+		}
+	}
+
+	rep.Summary = Summary{
+		ComputedAt:  now,
+		Users:       len(ids),
+		Friendships: friendships,
+		Components:  len(rep.Components),
+		Isolated:    len(rep.isolated),
+	}
+	if len(rep.Components) > 0 {
+		rep.Summary.LargestComponent = rep.Components[0].Size
+	}
+	if len(ids) > 0 {
+		rep.Summary.AverageDegree = 2 * float64(friendships) / float64(len(ids))
+		rep.Summary.AverageClustering = clusteringSum / float64(len(ids))
+	}
+	return rep
+}
+
+// clustering counts the friendships among id's friends against the number
+// possible. Each one is seen from both ends, hence the missing factor 2.
+func clustering(adj map[int]map[int]bool, id int) float64 {
+	k := len(adj[id])
+	if k < 2 {
+		return 0
+	}
+	links := 0
+	for a := range adj[id] {
+		for b := range adj[a] {
+			if adj[id][b] {
+				links++
+			}
+		}
+	}
+	return float64(links) / float64(k*(k-1))
+}
+
+// components finds the connected components with an iterative DFS; ids is
+// sorted, so each component is discovered from its lowest id.
+func components(adj map[int]map[int]bool, ids []int) []Component {
+	seen := make(map[int]bool, len(ids))
+	var comps []Component
+	for _, start := range ids {
+		if seen[start] {
+			continue
+		}
+		seen[start] = true
+		members := []int{}
+		stack := []int{start}
+		for len(stack) > 0 {
+			n := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			members = append(members, n)
+			for f := range adj[n] {
+				if !seen[f] {
+					seen[f] = true
+					stack = append(stack, f)
+				}
+			}
+		}
+		sort.Ints(members)
+		comps = append(comps, Component{Size: len(members), UserIDs: members})
+	}
+	sort.SliceStable(comps, func(i, j int) bool { return comps[i].Size > comps[j].Size })
+	for i := range comps {
+		comps[i].ID = i
+	}
+	return comps
+}
+
+// User returns the stats of one user, if the snapshot has them.
+func (r *Report) User(id int) (UserStats, bool) {
+	s, ok := r.users[id]
+	if !ok {
+		return UserStats{}, false
+	}
+	return *s, true
+}
+
+// TopUsers returns the n users with the most friends.
+func (r *Report) TopUsers(n int) []UserStats {
+	n = min(n, len(r.byDegree))
+	top := make([]UserStats, n)
+	for i := range top {
+		top[i] = *r.byDegree[i]
+	}
+	return top
+}
+
+// Isolated returns the ids of users without friends, ascending.
+func (r *Report) Isolated() []int {
+	return r.isolated
+}
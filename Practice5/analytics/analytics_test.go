@@ -0,0 +1,98 @@
+package analytics
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"practice5/models"
+)
+
+func TestAnalyze(t *testing.T) {
+	// A triangle 1-2-3 with a tail 3-4, a pair 5-6 and an isolated 7.
+	// The duplicate and the edge to unknown user 9 are ignored.
+	g := models.FriendGraph{
+		UserIDs: []int{1, 2, 3, 4, 5, 6, 7},
+		Edges:   [][2]int{{1, 2}, {1, 3}, {2, 3}, {3, 4}, {5, 6}, {2, 1}, {4, 9}},
+	}
+	rep := Analyze(g, time.Time{})
+
+	want := Summary{Users: 7, Friendships: 5, Components: 3, LargestComponent: 4, Isolated: 1,
+		AverageDegree: 10.0 / 7, AverageClustering: (1 + 1 + 1.0/3) / 7}
+	got := rep.Summary
+	if math.Abs(got.AverageClustering-want.AverageClustering) < 1e-9 {
+		got.AverageClustering = want.AverageClustering
+	}
+	if got != want {
+		t.Errorf("summary = %+v\nwant      %+v", got, want)
+	}
+
+	gotComps := [][]int{}
+	for _, c := range rep.Components {
+		gotComps = append(gotComps, c.UserIDs)
+	}
+	if want := [][]int{{1, 2, 3, 4}, {5, 6}, {7}}; !reflect.DeepEqual(gotComps, want) {
+		t.Errorf("components = %v; want %v", gotComps, want)
+	}
+
+	users := []UserStats{
+		{ID: 1, Degree: 2, Clustering: 1, Component: 0},
+		{ID: 3, Degree: 3, Clustering: 1.0 / 3, Component: 0},
+		{ID: 4, Degree: 1, Clustering: 0, Component: 0},
+		{ID: 6, Degree: 1, Clustering: 0, Component: 1},
+		{ID: 7, Degree: 0, Clustering: 0, Component: 2},
+	}
+	for _, want := range users {
+		if got, _ := rep.User(want.ID); got != want {
+			t.Errorf("User(%d) = %+v; want %+v", want.ID, got, want)
+		}
+	}
+	if _, ok := rep.User(9); ok {
+		t.Error("User(9) found; it is not in the snapshot")
+	}
+
+	var top []int
+	for _, u := range rep.TopUsers(3) {
+		top = append(top, u.ID)
+	}
+	if want := []int{3, 1, 2}; !reflect.DeepEqual(top, want) {
+		t.Errorf("top users = %v; want %v", top, want)
+	}
+	if got := rep.TopUsers(100); len(got) != 7 {
+		t.Errorf("TopUsers(100) returned %d users; want all 7", len(got))
+	}
+	if got := rep.Isolated(); !reflect.DeepEqual(got, []int{7}) {
+		t.Errorf("isolated = %v; want [7]", got)
+	}
+}
+
+func TestServiceRefresh(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	loads := 0
+	svc := NewService(func(context.Context) (models.FriendGraph, error) {
+		loads++
+		return models.FriendGraph{UserIDs: []int{1}}, nil
+	}, time.Minute)
+	svc.now = func() time.Time { return now }
+
+	ctx := context.Background()
+	for _, step := range []struct {
+		advance   time.Duration
+		wantLoads int
+	}{
+		{0, 1},
+		{30 * time.Second, 1},
+		{30 * time.Second, 2},
+		{59 * time.Second, 2},
+	} {
+		now = now.Add(step.advance)
+		if _, err := svc.Report(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if loads != step.wantLoads {
+			t.Fatalf("after %v: %d loads; want %d", step.advance, loads, step.wantLoads)
+		}
+	}
+}
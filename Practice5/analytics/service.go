@@ -0,0 +1,41 @@
+package analytics
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"practice5/models"
+)
+
+// Service keeps the latest Report and rebuilds it from a fresh snapshot
+// once it is older than the refresh interval. Only the caller that finds
+// the report stale pays for the rebuild; the others wait for it and share
+// the result.
+type Service struct {
+	load    func(ctx context.Context) (models.FriendGraph, error)
+	refresh time.Duration
+	now     func() time.Time
+
+	mu     sync.Mutex
+	report *Report
+}
+
+func NewService(load func(ctx context.Context) (models.FriendGraph, error), refresh time.Duration) *Service {
+	return &Service{load: load, refresh: refresh, now: time.Now}
+}
+
+// Report returns a report at most one refresh interval old.
+func (s *Service) Report(ctx context.Context) (*Report, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.report != nil && s.now().Sub(s.report.Summary.ComputedAt) < s.refresh {
+		return s.report, nil
+	}
+	g, err := s.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.report = Analyze(g, s.now())
+	return s.report, nil
+}
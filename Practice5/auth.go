@@ -0,0 +1,35 @@
+package main
+
+import (
+	"crypto/rsa"
+	"os"
+
+	"practice5/auth"
+)
+
+// loadVerifier builds the token verifier from the -jwt-* flags, falling back
+// to JWT_SECRET and JWT_PUBLIC_KEY_FILE. With neither set it returns nil and
+// the API stays open.
+func loadVerifier(secret, publicKeyFile string) (*auth.Verifier, error) {
+	if secret == "" {
+		secret = os.Getenv("JWT_SECRET")
+	}
+	if publicKeyFile == "" {
+		publicKeyFile = os.Getenv("JWT_PUBLIC_KEY_FILE")
+	}
+	if secret == "" && publicKeyFile == "" {
+		return nil, nil
+	}
+
+	var key *rsa.PublicKey
+	if publicKeyFile != "" {
+		data, err := os.ReadFile(publicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		if key, err = auth.ParsePublicKey(data); err != nil {
+			return nil, err
+		}
+	}
+	return auth.NewVerifier([]byte(secret), key)
+}
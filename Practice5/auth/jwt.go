@@ -0,0 +1,130 @@
+// Package auth verifies the bearer tokens Practice7 issues: JWTs signed
+// with HS256 or RS256 carrying user_id, role and exp claims. Parsing and
+// signature checks are golang-jwt's, as on the issuing side.
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrMalformed = errors.New("token is malformed")
+	ErrAlgorithm = errors.New("token algorithm is not accepted")
+	ErrSignature = errors.New("token signature is invalid")
+	ErrExpired   = errors.New("token has expired")
+)
+
+// Claims are the claims of a Practice7 token. user_id is a UUID there, but
+// any string or number is accepted.
+type Claims struct {
+	UserID    string
+	Role      string
+	ExpiresAt int64
+}
+
+// tokenClaims is the payload as golang-jwt decodes it.
+type tokenClaims struct {
+	UserID json.RawMessage `json:"user_id"`
+	Role   string          `json:"role"`
+	jwt.RegisteredClaims
+}
+
+// leeway absorbs clock skew between the issuer and this server.
+const leeway = 30 * time.Second
+
+// Verifier checks tokens against an HS256 secret, an RS256 public key, or
+// both. Only algorithms with a configured key are accepted, and a token is
+// checked with the key of the algorithm it names, so an RS256 public key
+// can never be used as an HS256 secret.
+type Verifier struct {
+	secret    []byte
+	publicKey *rsa.PublicKey
+	methods   []string
+	parser    *jwt.Parser
+	now       func() time.Time
+}
+
+// NewVerifier needs at least one of secret and publicKey.
+func NewVerifier(secret []byte, publicKey *rsa.PublicKey) (*Verifier, error) {
+	if len(secret) == 0 && publicKey == nil {
+		return nil, errors.New("auth: neither a secret nor a public key given")
+	}
+	v := &Verifier{secret: secret, publicKey: publicKey, now: time.Now}
+	if len(secret) > 0 {
+		v.methods = append(v.methods, jwt.SigningMethodHS256.Alg())
+	}
+	if publicKey != nil {
+		v.methods = append(v.methods, jwt.SigningMethodRS256.Alg())
+	}
+	v.parser = jwt.NewParser(
+		jwt.WithValidMethods(v.methods),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(leeway),
+		jwt.WithTimeFunc(func() time.Time { return v.now() }),
+	)
+	return v, nil
+}
+
+// Verify checks the signature and expiry of token and returns its claims.
+func (v *Verifier) Verify(token string) (Claims, error) {
+	var tc tokenClaims
+	t, err := v.parser.ParseWithClaims(token, &tc, v.key)
+	switch {
+	case err == nil:
+	case t != nil && !slices.Contains(v.methods, fmt.Sprint(t.Header["alg"])):
+		return Claims{}, ErrAlgorithm
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return Claims{}, ErrExpired
+	case errors.Is(err, jwt.ErrTokenSignatureInvalid):
+		return Claims{}, ErrSignature
+	default:
+		return Claims{}, fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+
+	c := Claims{Role: tc.Role, ExpiresAt: tc.ExpiresAt.Unix()}
+	if json.Unmarshal(tc.UserID, &c.UserID) != nil {
+		c.UserID = string(tc.UserID) // a number
+	}
+	return c, nil
+}
+
+// key hands golang-jwt the key for the token's algorithm, which the parser
+// has already checked against the configured ones.
+func (v *Verifier) key(t *jwt.Token) (interface{}, error) {
+	if t.Method == jwt.SigningMethodRS256 {
+		return v.publicKey, nil
+	}
+	return v.secret, nil
+}
+
+// ParsePublicKey reads an RSA public key from PEM: a PKIX "PUBLIC KEY", a
+// PKCS #1 "RSA PUBLIC KEY" or a certificate.
+func ParsePublicKey(data []byte) (*rsa.PublicKey, error) {
+	key, err := jwt.ParseRSAPublicKeyFromPEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("auth: %w", err)
+	}
+	return key, nil
+}
+
+type claimsKey struct{}
+
+// NewContext returns ctx carrying the claims of the caller's token.
+func NewContext(ctx context.Context, c Claims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, c)
+}
+
+// FromContext returns the caller's claims; ok is false for requests that
+// were not authenticated, including every request when auth is off.
+func FromContext(ctx context.Context) (c Claims, ok bool) {
+	c, ok = ctx.Value(claimsKey{}).(Claims)
+	return c, ok
+}
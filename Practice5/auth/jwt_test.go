@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// sign signs payload with golang-jwt, as Practice7 does.
+func sign(t *testing.T, alg, payload string, key interface{}) string {
+	t.Helper()
+	var claims jwt.MapClaims
+	if err := json.Unmarshal([]byte(payload), &claims); err != nil {
+		t.Fatal(err)
+	}
+	if alg == "none" {
+		key = jwt.UnsafeAllowNoneSignatureType
+	}
+	token, err := jwt.NewWithClaims(jwt.GetSigningMethod(alg), claims).SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestVerify(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	pub, err := ParsePublicKey(pemKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := []byte("s3cret")
+
+	now := time.Unix(1700000000, 0)
+	valid := `{"user_id":"0b6f3c4e-8f0e-4c1a-9a57-4d7c2a0f6a11","role":"admin","exp":1700003600}`
+	expired := `{"user_id":"u1","role":"user","exp":1699999000}`
+
+	tests := []struct {
+		name    string
+		secret  []byte
+		pub     *rsa.PublicKey
+		token   string
+		want    Claims
+		wantErr error
+	}{
+		{"hs256", secret, nil, sign(t, "HS256", valid, secret),
+			Claims{UserID: "0b6f3c4e-8f0e-4c1a-9a57-4d7c2a0f6a11", Role: "admin", ExpiresAt: 1700003600}, nil},
+		{"rs256", nil, pub, sign(t, "RS256", valid, rsaKey),
+			Claims{UserID: "0b6f3c4e-8f0e-4c1a-9a57-4d7c2a0f6a11", Role: "admin", ExpiresAt: 1700003600}, nil},
+		{"numeric user id", secret, nil, sign(t, "HS256", `{"user_id":7,"role":"user","exp":1700003600}`, secret),
+			Claims{UserID: "7", Role: "user", ExpiresAt: 1700003600}, nil},
+		{"wrong secret", secret, nil, sign(t, "HS256", valid, []byte("other")), Claims{}, ErrSignature},
+		{"expired", secret, nil, sign(t, "HS256", expired, secret), Claims{}, ErrExpired},
+		{"alg none", secret, pub, sign(t, "none", valid, nil), Claims{}, ErrAlgorithm},
+		// The classic confusion attack: the public key used as an HMAC secret.
+		{"hs256 with public key", nil, pub, sign(t, "HS256", valid, pemKey), Claims{}, ErrAlgorithm},
+		{"rs256 without key", secret, nil, sign(t, "RS256", valid, rsaKey), Claims{}, ErrAlgorithm},
+		{"not a jwt", secret, nil, "abc.def", Claims{}, ErrMalformed},
+		{"no exp", secret, nil, sign(t, "HS256", `{"user_id":"u1","role":"admin"}`, secret), Claims{}, ErrMalformed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := NewVerifier(tt.secret, tt.pub)
+			if err != nil {
+				t.Fatal(err)
+			}
+			v.now = func() time.Time { return now }
+			got, err := v.Verify(tt.token)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v; want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("claims = %+v; want %+v", got, tt.want)
+			}
+		})
+	}
+}
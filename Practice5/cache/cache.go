@@ -0,0 +1,15 @@
+// Package cache holds read-through caches for the user store. Entries carry
+// tags naming the data they were built from, so a write can drop exactly the
+// entries it affects instead of flushing everything.
+package cache
+
+// Cache is what repository.Cached needs from a cache. Implementations must be
+// safe for concurrent use. Values are shared between callers and must not be
+// modified.
+type Cache interface {
+	Get(key string) (value interface{}, ok bool)
+	Set(key string, value interface{}, tags []string)
+	// Invalidate drops every entry carrying at least one of tags.
+	Invalidate(tags ...string)
+	Len() int
+}
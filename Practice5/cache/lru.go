@@ -0,0 +1,109 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// LRU is an in-process Cache holding at most size entries; the least
+// recently used one makes room for a new one. With a ttl, entries also
+// expire, which bounds how stale a value written by another instance, or
+// straight to the database, can get.
+type LRU struct {
+	size int
+	ttl  time.Duration
+	now  func() time.Time
+
+	mu      sync.Mutex
+	order   *list.List               // front is most recently used
+	entries map[string]*list.Element // of *entry
+	byTag   map[string]map[string]struct{}
+}
+
+type entry struct {
+	key     string
+	value   interface{}
+	tags    []string
+	expires time.Time // zero without a ttl
+}
+
+func NewLRU(size int, ttl time.Duration) *LRU {
+	return &LRU{
+		size:    size,
+		ttl:     ttl,
+		now:     time.Now,
+		order:   list.New(),
+		entries: map[string]*list.Element{},
+		byTag:   map[string]map[string]struct{}{},
+	}
+}
+
+func (c *LRU) Get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*entry)
+	if !e.expires.IsZero() && c.now().After(e.expires) {
+		c.remove(el)
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return e.value, true
+}
+
+func (c *LRU) Set(key string, value interface{}, tags []string) {
+	if c.size <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		c.remove(el)
+	}
+	e := &entry{key: key, value: value, tags: tags}
+	if c.ttl > 0 {
+		e.expires = c.now().Add(c.ttl)
+	}
+	c.entries[key] = c.order.PushFront(e)
+	for _, t := range tags {
+		if c.byTag[t] == nil {
+			c.byTag[t] = map[string]struct{}{}
+		}
+		c.byTag[t][key] = struct{}{}
+	}
+	for c.order.Len() > c.size {
+		c.remove(c.order.Back())
+	}
+}
+
+func (c *LRU) Invalidate(tags ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, t := range tags {
+		for key := range c.byTag[t] {
+			c.remove(c.entries[key])
+		}
+	}
+}
+
+func (c *LRU) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// remove unlinks el from the list, the key index and every tag index.
+func (c *LRU) remove(el *list.Element) {
+	e := c.order.Remove(el).(*entry)
+	delete(c.entries, e.key)
+	for _, t := range e.tags {
+		delete(c.byTag[t], e.key)
+		if len(c.byTag[t]) == 0 {
+			delete(c.byTag, t)
+		}
+	}
+}
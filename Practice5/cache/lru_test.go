@@ -0,0 +1,65 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLRUEvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewLRU(2, 0)
+	c.Set("a", 1, nil)
+	c.Set("b", 2, nil)
+	c.Get("a") // b is now the oldest
+	c.Set("c", 3, nil)
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("b survived; want it evicted")
+	}
+	for _, k := range []string{"a", "c"} {
+		if _, ok := c.Get(k); !ok {
+			t.Errorf("%s was evicted", k)
+		}
+	}
+	if c.Len() != 2 {
+		t.Errorf("Len = %d; want 2", c.Len())
+	}
+}
+
+func TestLRUInvalidate(t *testing.T) {
+	c := NewLRU(10, 0)
+	c.Set("common|1|2", "x", []string{"friends:1", "friends:2"})
+	c.Set("common|2|3", "y", []string{"friends:2", "friends:3"})
+	c.Set("common|3|4", "z", []string{"friends:3", "friends:4"})
+
+	c.Invalidate("friends:1", "friends:4")
+	if _, ok := c.Get("common|2|3"); !ok {
+		t.Error("untouched entry was dropped")
+	}
+	for _, k := range []string{"common|1|2", "common|3|4"} {
+		if _, ok := c.Get(k); ok {
+			t.Errorf("%s survived invalidation", k)
+		}
+	}
+	if len(c.byTag) != 2 {
+		t.Errorf("tag index has %d tags; want only those of the remaining entry", len(c.byTag))
+	}
+}
+
+func TestLRUExpiry(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewLRU(10, time.Minute)
+	c.now = func() time.Time { return now }
+
+	c.Set("a", 1, []string{"users"})
+	now = now.Add(59 * time.Second)
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("entry expired early")
+	}
+	now = now.Add(2 * time.Second)
+	if _, ok := c.Get("a"); ok {
+		t.Fatal("entry outlived its ttl")
+	}
+	if c.Len() != 0 || len(c.byTag) != 0 {
+		t.Errorf("expired entry left behind: Len %d, %d tags", c.Len(), len(c.byTag))
+	}
+}
@@ -0,0 +1,311 @@
+package db
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/lib/pq"
+	"gopkg.in/yaml.v3"
+)
+
+// Config describes how to reach Postgres and how to size the pool.
+// Either DSN or the individual connection fields are used, not both.
+type Config struct {
+	DSN      string `yaml:"dsn"`
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	User     string `yaml:"user"`
+	Password string `yaml:"password"`
+	Name     string `yaml:"name"`
+	SSLMode  string `yaml:"sslmode"`
+
+	MaxOpenConns    int           `yaml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time"`
+
+	// ConnectAttempts bounds how often the first ping is tried, waiting
+	// RetryBackoff, then twice as long, and so on (capped at maxBackoff).
+	ConnectAttempts int           `yaml:"connect_attempts"`
+	RetryBackoff    time.Duration `yaml:"retry_backoff"`
+
+	// CreateDatabase issues CREATE DATABASE against the postgres admin DB
+	// when the target database does not exist yet.
+	CreateDatabase bool `yaml:"create_database"`
+}
+
+// DefaultConfig matches a stock local Postgres and the README.
+func DefaultConfig() Config {
+	return Config{
+		Host:            "localhost",
+		Port:            5432,
+		User:            "postgres",
+		Password:        "postgres",
+		Name:            "practice5",
+		SSLMode:         "disable",
+		MaxOpenConns:    25,
+		MaxIdleConns:    10,
+		ConnMaxLifetime: 30 * time.Minute,
+		ConnMaxIdleTime: 5 * time.Minute,
+		ConnectAttempts: 5,
+		RetryBackoff:    500 * time.Millisecond,
+	}
+}
+
+// ConnString returns the lib/pq connection string for the configured
+// database, always in key=value form.
+func (c Config) ConnString() (string, error) {
+	if c.DSN == "" {
+		return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+			quoteOpt(c.Host), c.Port, quoteOpt(c.User), quoteOpt(c.Password), quoteOpt(c.Name), quoteOpt(c.SSLMode)), nil
+	}
+	if strings.HasPrefix(c.DSN, "postgres://") || strings.HasPrefix(c.DSN, "postgresql://") {
+		return pq.ParseURL(c.DSN)
+	}
+	return c.DSN, nil
+}
+
+// adminConnString points the same connection at the postgres database, for
+// CREATE DATABASE. lib/pq lets a later key override an earlier one.
+func (c Config) adminConnString() (string, error) {
+	dsn, err := c.ConnString()
+	if err != nil {
+		return "", err
+	}
+	return dsn + " dbname=postgres", nil
+}
+
+// databaseName is the dbname the connection string ends up using.
+func (c Config) databaseName() (string, error) {
+	if c.DSN == "" {
+		return c.Name, nil
+	}
+	dsn, err := c.ConnString()
+	if err != nil {
+		return "", err
+	}
+	opts, err := parseConnString(dsn)
+	if err != nil {
+		return "", err
+	}
+	if opts["dbname"] == "" {
+		return "", fmt.Errorf("dsn does not name a database")
+	}
+	return opts["dbname"], nil
+}
+
+// parseConnString splits a key=value connection string the way lib/pq
+// does: spaces may surround "=", a value may be single-quoted to hold
+// spaces, a backslash escapes the next character and a later key
+// overrides an earlier one.
+func parseConnString(dsn string) (map[string]string, error) {
+	opts := map[string]string{}
+	r := []rune(dsn)
+	i := 0
+	skipSpace := func() {
+		for i < len(r) && unicode.IsSpace(r[i]) {
+			i++
+		}
+	}
+	for {
+		skipSpace()
+		if i == len(r) {
+			return opts, nil
+		}
+		start := i
+		for i < len(r) && r[i] != '=' && !unicode.IsSpace(r[i]) {
+			i++
+		}
+		key := string(r[start:i])
+		skipSpace()
+		if i == len(r) || r[i] != '=' {
+			return nil, fmt.Errorf("dsn: missing \"=\" after %q", key)
+		}
+		i++
+		skipSpace()
+
+		quoted := i < len(r) && r[i] == '\''
+		if quoted {
+			i++
+		}
+		var val []rune
+		for ; i < len(r); i++ {
+			if quoted && r[i] == '\'' || !quoted && unicode.IsSpace(r[i]) {
+				break
+			}
+			if r[i] == '\\' {
+				if i++; i == len(r) {
+					break
+				}
+			}
+			val = append(val, r[i])
+		}
+		if quoted {
+			if i == len(r) {
+				return nil, fmt.Errorf("dsn: unterminated quoted value for %q", key)
+			}
+			i++
+		}
+		opts[key] = string(val)
+	}
+}
+
+func quoteOpt(s string) string {
+	if s != "" && !strings.ContainsAny(s, ` '\`) {
+		return s
+	}
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
+
+// option ties one Config field to its environment variable and flag.
+type option struct {
+	name  string // flag name without the db- prefix
+	env   string
+	usage string
+	field func(c *Config) interface{} // pointer to the field
+}
+
+var options = []option{
+	{"dsn", "DB_DSN", "connection string or postgres:// URL; overrides host, port, user, password, name and sslmode", func(c *Config) interface{} { return &c.DSN }},
+	{"host", "DB_HOST", "database host", func(c *Config) interface{} { return &c.Host }},
+	{"port", "DB_PORT", "database port", func(c *Config) interface{} { return &c.Port }},
+	{"user", "DB_USER", "database user", func(c *Config) interface{} { return &c.User }},
+	{"password", "DB_PASSWORD", "database password", func(c *Config) interface{} { return &c.Password }},
+	{"name", "DB_NAME", "database name", func(c *Config) interface{} { return &c.Name }},
+	{"sslmode", "DB_SSLMODE", "sslmode passed to lib/pq", func(c *Config) interface{} { return &c.SSLMode }},
+	{"max-open-conns", "DB_MAX_OPEN_CONNS", "maximum open connections (0 = unlimited)", func(c *Config) interface{} { return &c.MaxOpenConns }},
+	{"max-idle-conns", "DB_MAX_IDLE_CONNS", "maximum idle connections kept in the pool", func(c *Config) interface{} { return &c.MaxIdleConns }},
+	{"conn-max-lifetime", "DB_CONN_MAX_LIFETIME", "close connections older than this (0 = never)", func(c *Config) interface{} { return &c.ConnMaxLifetime }},
+	{"conn-max-idle-time", "DB_CONN_MAX_IDLE_TIME", "close connections idle for longer than this (0 = never)", func(c *Config) interface{} { return &c.ConnMaxIdleTime }},
+	{"connect-attempts", "DB_CONNECT_ATTEMPTS", "how often to try the first connection", func(c *Config) interface{} { return &c.ConnectAttempts }},
+	{"retry-backoff", "DB_RETRY_BACKOFF", "wait before the second connection attempt; doubles after each failure", func(c *Config) interface{} { return &c.RetryBackoff }},
+	{"create", "DB_CREATE", "create the database if it does not exist", func(c *Config) interface{} { return &c.CreateDatabase }},
+}
+
+// setField parses s into the field ptr points to.
+func setField(ptr interface{}, s string) error {
+	switch p := ptr.(type) {
+	case *string:
+		*p = s
+	case *int:
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return err
+		}
+		*p = n
+	case *bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		*p = b
+	case *time.Duration:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		*p = d
+	}
+	return nil
+}
+
+// fieldValue adapts a Config field to flag.Value.
+type fieldValue struct{ ptr interface{} }
+
+func (v fieldValue) String() string {
+	if v.ptr == nil {
+		return ""
+	}
+	switch p := v.ptr.(type) {
+	case *string:
+		return *p
+	case *int:
+		return strconv.Itoa(*p)
+	case *bool:
+		return strconv.FormatBool(*p)
+	case *time.Duration:
+		return p.String()
+	}
+	return ""
+}
+
+func (v fieldValue) Set(s string) error { return setField(v.ptr, s) }
+
+// IsBoolFlag lets -db-create be passed without a value.
+func (v fieldValue) IsBoolFlag() bool {
+	_, ok := v.ptr.(*bool)
+	return ok
+}
+
+// Flags holds the -db-* flags registered on a FlagSet.
+type Flags struct {
+	fs     *flag.FlagSet
+	file   string
+	parsed Config
+}
+
+// RegisterFlags adds -db-config and one -db-<option> flag per Config field
+// to fs. Call Config after fs has been parsed.
+func RegisterFlags(fs *flag.FlagSet) *Flags {
+	f := &Flags{fs: fs, parsed: DefaultConfig()}
+	fs.StringVar(&f.file, "db-config", "", "YAML file with database settings (env DB_CONFIG)")
+	for _, o := range options {
+		fs.Var(fieldValue{o.field(&f.parsed)}, "db-"+o.name, o.usage+" (env "+o.env+")")
+	}
+	return f
+}
+
+// Config resolves the settings. Later sources win: defaults, the YAML file,
+// DB_* environment variables, then flags given on the command line.
+func (f *Flags) Config() (Config, error) {
+	cfg := DefaultConfig()
+
+	path := f.file
+	if path == "" {
+		path = os.Getenv("DB_CONFIG")
+	}
+	if path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return cfg, err
+		}
+	}
+
+	for _, o := range options {
+		if v := os.Getenv(o.env); v != "" {
+			if err := setField(o.field(&cfg), v); err != nil {
+				return cfg, fmt.Errorf("%s: %w", o.env, err)
+			}
+		}
+	}
+
+	var err error
+	f.fs.Visit(func(fl *flag.Flag) {
+		for _, o := range options {
+			if fl.Name == "db-"+o.name && err == nil {
+				err = setField(o.field(&cfg), fl.Value.String())
+			}
+		}
+	})
+	return cfg, err
+}
+
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
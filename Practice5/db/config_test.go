@@ -0,0 +1,110 @@
+package db
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestConfigPrecedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db.yaml")
+	yaml := "host: db.internal\nport: 6543\nname: fromfile\nmax_open_conns: 50\nconn_max_lifetime: 1h\n"
+	if err := os.WriteFile(path, []byte(yaml), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("DB_CONFIG", path)
+	t.Setenv("DB_NAME", "fromenv")
+	t.Setenv("DB_PORT", "7000")
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	f := RegisterFlags(fs)
+	if err := fs.Parse([]string{"-db-port", "8000", "-db-create"}); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := f.Config()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := DefaultConfig()
+	want.Host = "db.internal"        // file
+	want.ConnMaxLifetime = time.Hour // file
+	want.MaxOpenConns = 50           // file
+	want.Name = "fromenv"            // env over file
+	want.Port = 8000                 // flag over env
+	want.CreateDatabase = true       // flag
+	if cfg != want {
+		t.Errorf("config = %+v\nwant   %+v", cfg, want)
+	}
+}
+
+func TestConfigFileRejectsUnknownKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db.yaml")
+	if err := os.WriteFile(path, []byte("hots: typo\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	f := RegisterFlags(fs)
+	if err := fs.Parse([]string{"-db-config", path}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Config(); err == nil {
+		t.Error("expected an error for an unknown key")
+	}
+}
+
+func TestConnString(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfg       Config
+		want      string
+		wantAdmin string
+		wantDB    string
+	}{
+		{"fields", DefaultConfig(),
+			"host=localhost port=5432 user=postgres password=postgres dbname=practice5 sslmode=disable",
+			"host=localhost port=5432 user=postgres password=postgres dbname=practice5 sslmode=disable dbname=postgres",
+			"practice5"},
+		{"quoted password", Config{Host: "h", Port: 1, User: "u", Password: "it's secret", Name: "n", SSLMode: "disable"},
+			`host=h port=1 user=u password='it\'s secret' dbname=n sslmode=disable`,
+			`host=h port=1 user=u password='it\'s secret' dbname=n sslmode=disable dbname=postgres`,
+			"n"},
+		{"url", Config{DSN: "postgres://u:p@h:6000/app?sslmode=require"},
+			"dbname='app' host='h' password='p' port='6000' sslmode='require' user='u'",
+			"dbname='app' host='h' password='p' port='6000' sslmode='require' user='u' dbname=postgres",
+			"app"},
+		{"key value with quoted spaces", Config{DSN: `host=h password='a dbname=x' dbname = 'my app'`},
+			`host=h password='a dbname=x' dbname = 'my app'`,
+			`host=h password='a dbname=x' dbname = 'my app' dbname=postgres`,
+			"my app"},
+		{"escaped quote", Config{DSN: `dbname='it\'s'`},
+			`dbname='it\'s'`,
+			`dbname='it\'s' dbname=postgres`,
+			"it's"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.cfg.ConnString()
+			if err != nil || got != tt.want {
+				t.Errorf("ConnString() = %q, %v; want %q", got, err, tt.want)
+			}
+			admin, _ := tt.cfg.adminConnString()
+			if admin != tt.wantAdmin {
+				t.Errorf("adminConnString() = %q; want %q", admin, tt.wantAdmin)
+			}
+			if name, _ := tt.cfg.databaseName(); name != tt.wantDB {
+				t.Errorf("databaseName() = %q; want %q", name, tt.wantDB)
+			}
+		})
+	}
+}
+
+func TestParseConnStringErrors(t *testing.T) {
+	for _, dsn := range []string{"host", "host=h dbname", "dbname='open"} {
+		if _, err := parseConnString(dsn); err == nil {
+			t.Errorf("parseConnString(%q) succeeded; want an error", dsn)
+		}
+	}
+}
@@ -1,128 +1,105 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
-	"os"
+	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
-func Connect() *sql.DB {
-	dbName := getEnv("DB_NAME", "practice5")
+// maxBackoff caps the wait between connection attempts.
+const maxBackoff = 10 * time.Second
 
-	adminDSN := fmt.Sprintf(
-		"host=%s port=%s user=%s password=%s dbname=postgres sslmode=disable",
-		getEnv("DB_HOST", "localhost"),
-		getEnv("DB_PORT", "5433"),
-		getEnv("DB_USER", "postgres"),
-		getEnv("DB_PASSWORD", "zhangir"),
-	)
-	adminDB, err := sql.Open("postgres", adminDSN)
+// Connect opens the pool described by cfg and waits until the database
+// answers, retrying with exponential backoff. With cfg.CreateDatabase set
+// it first creates the database if it is missing.
+func Connect(ctx context.Context, cfg Config) (*sql.DB, error) {
+	dsn, err := cfg.ConnString()
 	if err != nil {
-		log.Fatal("Failed to open admin DB:", err)
+		return nil, fmt.Errorf("parse dsn: %w", err)
 	}
-	_, err = adminDB.Exec(fmt.Sprintf(`CREATE DATABASE "%s"`, dbName))
-	if err != nil {
-		
-		log.Println("ℹ️  Database already exists or could not be created:", err)
-	} else {
-		log.Println("✅ Database created:", dbName)
+	if cfg.CreateDatabase {
+		if err := createDatabase(ctx, cfg); err != nil {
+			return nil, err
+		}
 	}
-	adminDB.Close()
 
-	dsn := fmt.Sprintf(
-		"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		getEnv("DB_HOST", "localhost"),
-		getEnv("DB_PORT", "5433"),
-		getEnv("DB_USER", "postgres"),
-		getEnv("DB_PASSWORD", "zhangir"),
-		dbName,
-	)
 	db, err := sql.Open("postgres", dsn)
 	if err != nil {
-		log.Fatal("Failed to open DB:", err)
-	}
-	if err = db.Ping(); err != nil {
-		log.Fatal("Failed to ping DB:", err)
+		return nil, err
 	}
-	log.Println("✅ Connected to database:", dbName)
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
 
-	Migrate(db)
-
-	return db
+	if err := withRetry(ctx, cfg, "connect", db.PingContext); err != nil {
+		db.Close()
+		return nil, err
+	}
+	name, _ := cfg.databaseName()
+	log.Println("✅ Connected to database:", name)
+	return db, nil
 }
 
-func Migrate(db *sql.DB) {
-	schema := `
-	CREATE TABLE IF NOT EXISTS users (
-		id        SERIAL PRIMARY KEY,
-		name      VARCHAR(100) NOT NULL,
-		email     VARCHAR(100) UNIQUE NOT NULL,
-		gender    VARCHAR(10)  NOT NULL,
-		birthdate DATE         NOT NULL
-	);
-
-	CREATE TABLE IF NOT EXISTS user_friends (
-		user_id   INTEGER REFERENCES users(id) ON DELETE CASCADE,
-		friend_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
-		PRIMARY KEY (user_id, friend_id),
-		CHECK (user_id <> friend_id)
-	);
-	`
-	if _, err := db.Exec(schema); err != nil {
-		log.Fatal("Migration failed:", err)
+// withRetry runs fn up to cfg.ConnectAttempts times, doubling the wait
+// between attempts from cfg.RetryBackoff up to maxBackoff.
+func withRetry(ctx context.Context, cfg Config, what string, fn func(context.Context) error) error {
+	attempts := max(cfg.ConnectAttempts, 1)
+	wait := cfg.RetryBackoff
+	var err error
+	for i := 1; ; i++ {
+		if err = fn(ctx); err == nil {
+			return nil
+		}
+		if i == attempts {
+			return fmt.Errorf("%s: giving up after %d attempts: %w", what, attempts, err)
+		}
+		log.Printf("⏳ %s attempt %d/%d failed: %v; retrying in %s", what, i, attempts, err, wait)
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return fmt.Errorf("%s: %w", what, ctx.Err())
+		}
+		wait = min(wait*2, maxBackoff)
 	}
-	log.Println("✅ Tables ready")
+}
 
-	var count int
-	db.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&count)
-	if count > 0 {
-		log.Println("ℹ️  Data already seeded, skipping")
-		return
+// createDatabase creates the configured database through the postgres
+// admin database unless it already exists.
+func createDatabase(ctx context.Context, cfg Config) error {
+	name, err := cfg.databaseName()
+	if err != nil {
+		return err
 	}
-
-	seed := `
-	INSERT INTO users (name, email, gender, birthdate) VALUES
-	('Alice Johnson',  'alice@mail.com',   'female', '1995-03-12'),
-	('Bob Smith',      'bob@mail.com',     'male',   '1993-07-22'),
-	('Carol White',    'carol@mail.com',   'female', '1998-01-05'),
-	('David Brown',    'david@mail.com',   'male',   '1990-11-30'),
-	('Eva Green',      'eva@mail.com',     'female', '1997-06-18'),
-	('Frank Miller',   'frank@mail.com',   'male',   '1992-09-14'),
-	('Grace Lee',      'grace@mail.com',   'female', '1999-04-25'),
-	('Henry Wilson',   'henry@mail.com',   'male',   '1994-02-08'),
-	('Iris Davis',     'iris@mail.com',    'female', '1996-08-17'),
-	('Jack Taylor',    'jack@mail.com',    'male',   '1991-12-03'),
-	('Karen Moore',    'karen@mail.com',   'female', '2000-05-20'),
-	('Leo Anderson',   'leo@mail.com',     'male',   '1993-10-11'),
-	('Mia Thomas',     'mia@mail.com',     'female', '1998-07-29'),
-	('Noah Jackson',   'noah@mail.com',    'male',   '1995-03-06'),
-	('Olivia Harris',  'olivia@mail.com',  'female', '1997-01-15'),
-	('Paul Martinez',  'paul@mail.com',    'male',   '1989-11-22'),
-	('Quinn Robinson', 'quinn@mail.com',   'female', '2001-09-09'),
-	('Ryan Clark',     'ryan@mail.com',    'male',   '1992-04-18'),
-	('Sophia Lewis',   'sophia@mail.com',  'female', '1996-06-27'),
-	('Tom Walker',     'tom@mail.com',     'male',   '1994-08-01');
-
-	-- Alice(1) and Bob(2) share 3 common friends: Carol(3), David(4), Eva(5)
-	INSERT INTO user_friends (user_id, friend_id) VALUES
-	(1,3),(3,1),(1,4),(4,1),(1,5),(5,1),
-	(2,3),(3,2),(2,4),(4,2),(2,5),(5,2),
-	(1,2),(2,1),
-	(6,7),(7,6),(8,9),(9,8),(10,11),(11,10),
-	(12,13),(13,12),(14,15),(15,14);
-	`
-	if _, err := db.Exec(seed); err != nil {
-		log.Fatal("Seeding failed:", err)
+	adminDSN, err := cfg.adminConnString()
+	if err != nil {
+		return err
 	}
-	log.Println("✅ Seeded 20 users and friendships")
-}
+	admin, err := sql.Open("postgres", adminDSN)
+	if err != nil {
+		return err
+	}
+	defer admin.Close()
 
-func getEnv(key, fallback string) string {
-	if v := os.Getenv(key); v != "" {
-		return v
+	var exists bool
+	err = withRetry(ctx, cfg, "connect to admin database", func(ctx context.Context) error {
+		return admin.QueryRowContext(ctx,
+			`SELECT EXISTS (SELECT 1 FROM pg_database WHERE datname = $1)`, name,
+		).Scan(&exists)
+	})
+	if err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+	if _, err := admin.ExecContext(ctx, `CREATE DATABASE `+pq.QuoteIdentifier(name)); err != nil {
+		return fmt.Errorf("create database %s: %w", name, err)
 	}
-	return fallback
+	log.Println("✅ Database created:", name)
+	return nil
 }
@@ -0,0 +1,66 @@
+package db
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"practice5/migrations"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0002_add_index.up.sql":      {Data: []byte("CREATE INDEX ...")},
+		"0002_add_index.down.sql":    {Data: []byte("DROP INDEX ...")},
+		"0001_create_users.up.sql":   {Data: []byte("CREATE TABLE users ...")},
+		"0001_create_users.down.sql": {Data: []byte("DROP TABLE users")},
+		"README.md":                  {Data: []byte("ignored")},
+	}
+
+	got, err := loadMigrations(fsys)
+	if err != nil {
+		t.Fatalf("loadMigrations: %v", err)
+	}
+	if len(got) != 2 || got[0].Version != 1 || got[1].Version != 2 {
+		t.Fatalf("got %+v; want versions 1, 2 in order", got)
+	}
+	if got[1].Name != "add_index" || got[1].Down != "DROP INDEX ..." {
+		t.Errorf("migration 2 = %+v", got[1])
+	}
+}
+
+func TestLoadMigrationsErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+		want string
+	}{
+		{"missing down", fstest.MapFS{
+			"0001_a.up.sql": {Data: []byte("x")},
+		}, "needs both"},
+		{"name mismatch", fstest.MapFS{
+			"0001_a.up.sql":   {Data: []byte("x")},
+			"0001_b.down.sql": {Data: []byte("x")},
+		}, "two names"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadMigrations(tt.fsys)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v; want it to mention %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	got, err := loadMigrations(migrations.FS)
+	if err != nil {
+		t.Fatalf("loadMigrations: %v", err)
+	}
+	for i, m := range got {
+		if m.Version != i+1 {
+			t.Errorf("migration %d has version %d; versions must be contiguous from 1", i, m.Version)
+		}
+	}
+}
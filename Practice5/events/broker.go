@@ -0,0 +1,156 @@
+// Package events fans change notifications out to GET /events subscribers.
+// Postgres announces row changes with NOTIFY (see migration 0004); a
+// Listener feeds them into a Broker, which numbers them, keeps the most
+// recent ones for Last-Event-ID replay and hands them to every subscriber.
+package events
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// Event types. TypeReset tells clients that events may have been lost, e.g.
+// while the database connection was down or because they reconnected with
+// an id older than the replay buffer, so they should reload their state.
+const (
+	TypeUserCreated       = "user.created"
+	TypeUserUpdated       = "user.updated"
+	TypeFriendshipAdded   = "friendship.added"
+	TypeFriendshipRemoved = "friendship.removed"
+	TypeReset             = "stream.reset"
+)
+
+type Event struct {
+	ID   uint64
+	Type string
+	Data json.RawMessage
+}
+
+// Broker is safe for concurrent use.
+type Broker struct {
+	clientBuffer int
+
+	mu     sync.Mutex
+	lastID uint64
+	ring   []Event // the most recent events, oldest first
+	size   int
+	subs   map[*Subscription]struct{}
+	closed bool
+}
+
+// NewBroker keeps the last size events for replay and lets each subscriber
+// fall clientBuffer events behind before it is dropped.
+//
+// Ids start at the current time in microseconds rather than at 1, so ids
+// from before a restart are older than anything in the new buffer and get a
+// reset instead of a wrong replay.
+func NewBroker(size, clientBuffer int) *Broker {
+	return &Broker{
+		clientBuffer: clientBuffer,
+		lastID:       uint64(time.Now().UnixMicro()),
+		size:         size,
+		subs:         map[*Subscription]struct{}{},
+	}
+}
+
+// Subscription receives events on C until Done is closed: because the
+// subscriber fell too far behind (Dropped reports true), was cancelled, or
+// the broker shut down.
+type Subscription struct {
+	C    <-chan Event
+	Done <-chan struct{}
+
+	c       chan Event
+	done    chan struct{}
+	dropped bool
+}
+
+func (s *Subscription) Dropped() bool { return s.dropped }
+
+// Publish numbers the event, buffers it and delivers it to every
+// subscriber without waiting: a subscriber whose channel is full is
+// dropped, so one slow client never holds up the others.
+func (b *Broker) Publish(typ string, data json.RawMessage) Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.lastID++
+	e := Event{ID: b.lastID, Type: typ, Data: data}
+	if b.size > 0 {
+		if len(b.ring) == b.size {
+			b.ring = b.ring[1:] // append reallocates once capacity runs out
+		}
+		b.ring = append(b.ring, e)
+	}
+	for s := range b.subs {
+		select {
+		case s.c <- e:
+		default:
+			s.dropped = true
+			b.remove(s)
+		}
+	}
+	return e
+}
+
+// Subscribe registers a subscriber. With resume set it also returns what the
+// client missed since lastID (from Last-Event-ID): the buffered events after
+// it, or a single reset event when some of them are no longer buffered.
+func (b *Broker) Subscribe(lastID uint64, resume bool) (*Subscription, []Event) {
+	c, done := make(chan Event, b.clientBuffer), make(chan struct{})
+	s := &Subscription{C: c, Done: done, c: c, done: done}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		close(done)
+		return s, nil
+	}
+	b.subs[s] = struct{}{}
+	if !resume {
+		return s, nil
+	}
+
+	if lastID > b.lastID || (lastID < b.lastID && (len(b.ring) == 0 || lastID+1 < b.ring[0].ID)) {
+		return s, []Event{{ID: b.lastID, Type: TypeReset, Data: json.RawMessage(`{}`)}}
+	}
+	var replay []Event
+	for _, e := range b.ring {
+		if e.ID > lastID {
+			replay = append(replay, e)
+		}
+	}
+	return s, replay
+}
+
+// Unsubscribe removes s; it is fine to call after s was dropped.
+func (b *Broker) Unsubscribe(s *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.subs[s]; ok {
+		b.remove(s)
+	}
+}
+
+// Close ends every subscription, so open streams finish and the server can
+// shut down.
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for s := range b.subs {
+		b.remove(s)
+	}
+}
+
+// Subscribers returns the number of connected subscribers.
+func (b *Broker) Subscribers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs)
+}
+
+func (b *Broker) remove(s *Subscription) {
+	delete(b.subs, s)
+	close(s.done)
+}
@@ -0,0 +1,120 @@
+package events
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func publishN(b *Broker, n int) []Event {
+	var evs []Event
+	for i := 0; i < n; i++ {
+		evs = append(evs, b.Publish(TypeUserCreated, json.RawMessage(`{}`)))
+	}
+	return evs
+}
+
+func TestSubscribeReplay(t *testing.T) {
+	b := NewBroker(5, 10)
+	evs := publishN(b, 8) // only the last 5 are buffered
+
+	for _, tt := range []struct {
+		name      string
+		lastID    uint64
+		resume    bool
+		wantIDs   []uint64
+		wantReset bool
+	}{
+		{"fresh", 0, false, nil, false},
+		{"up to date", evs[7].ID, true, nil, false},
+		{"missed two", evs[5].ID, true, []uint64{evs[6].ID, evs[7].ID}, false},
+		{"oldest buffered is next", evs[2].ID, true, []uint64{evs[3].ID, evs[4].ID, evs[5].ID, evs[6].ID, evs[7].ID}, false},
+		{"gap", evs[1].ID, true, nil, true},
+		{"id from the future", evs[7].ID + 100, true, nil, true},
+		{"id from before a restart", 1, true, nil, true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			s, replay := b.Subscribe(tt.lastID, tt.resume)
+			defer b.Unsubscribe(s)
+			if tt.wantReset {
+				if len(replay) != 1 || replay[0].Type != TypeReset {
+					t.Fatalf("replay = %v; want a single reset", replay)
+				}
+				return
+			}
+			var ids []uint64
+			for _, e := range replay {
+				ids = append(ids, e.ID)
+			}
+			if len(ids) != len(tt.wantIDs) {
+				t.Fatalf("replayed %v; want %v", ids, tt.wantIDs)
+			}
+			for i := range ids {
+				if ids[i] != tt.wantIDs[i] {
+					t.Fatalf("replayed %v; want %v", ids, tt.wantIDs)
+				}
+			}
+		})
+	}
+}
+
+func TestSlowSubscriberIsDropped(t *testing.T) {
+	b := NewBroker(10, 2)
+	slow, _ := b.Subscribe(0, false)
+	fast, _ := b.Subscribe(0, false)
+
+	for i := 0; i < 3; i++ {
+		b.Publish(TypeUserUpdated, json.RawMessage(`{}`))
+		<-fast.C
+	}
+
+	select {
+	case <-slow.Done:
+	default:
+		t.Fatal("slow subscriber is still connected")
+	}
+	if !slow.Dropped() {
+		t.Error("Dropped() = false for the slow subscriber")
+	}
+	if fast.Dropped() {
+		t.Error("the subscriber that kept up was dropped")
+	}
+	if n := b.Subscribers(); n != 1 {
+		t.Errorf("Subscribers() = %d; want 1", n)
+	}
+	b.Unsubscribe(slow) // must not close Done twice
+}
+
+func TestClose(t *testing.T) {
+	b := NewBroker(10, 2)
+	s, _ := b.Subscribe(0, false)
+	b.Close()
+
+	select {
+	case <-s.Done:
+	default:
+		t.Fatal("Close left a subscription open")
+	}
+	if s.Dropped() {
+		t.Error("Dropped() = true after Close")
+	}
+	late, _ := b.Subscribe(0, false)
+	select {
+	case <-late.Done:
+	default:
+		t.Fatal("subscribing after Close returned an open subscription")
+	}
+}
+
+func TestPublishPayload(t *testing.T) {
+	b := NewBroker(10, 2)
+	s, _ := b.Subscribe(0, false)
+	publish(b, `{"type":"friendship.added","user_id":1,"friend_id":2}`)
+
+	e := <-s.C
+	if e.Type != TypeFriendshipAdded {
+		t.Errorf("Type = %q; want %q", e.Type, TypeFriendshipAdded)
+	}
+	if want := `{"friend_id":2,"user_id":1}`; string(e.Data) != want {
+		t.Errorf("Data = %s; want %s", e.Data, want)
+	}
+}
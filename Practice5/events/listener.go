@@ -0,0 +1,68 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Channel is the NOTIFY channel the migration 0004 triggers announce on.
+const Channel = "practice5_events"
+
+const (
+	minReconnect = time.Second
+	maxReconnect = time.Minute
+	pingInterval = 90 * time.Second
+)
+
+// Listen forwards notifications on Channel to b until ctx is done. It holds
+// its own connection, outside the pool; lib/pq reconnects it after
+// failures, and since notifications sent meanwhile are lost, subscribers
+// then get a reset event.
+func Listen(ctx context.Context, dsn string, b *Broker) error {
+	l := pq.NewListener(dsn, minReconnect, maxReconnect, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Println("⚠️  events listener:", err)
+		}
+	})
+	defer l.Close()
+	if err := l.Listen(Channel); err != nil {
+		return err
+	}
+
+	ping := time.NewTicker(pingInterval)
+	defer ping.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case n := <-l.Notify:
+			if n == nil { // the connection was re-established
+				b.Publish(TypeReset, json.RawMessage(`{}`))
+				continue
+			}
+			publish(b, n.Extra)
+		case <-ping.C:
+			// Notices a dead connection even when nothing is being sent.
+			go l.Ping()
+		}
+	}
+}
+
+// publish forwards one trigger payload, {"type": ..., ...}; the rest of the
+// payload becomes the event data.
+func publish(b *Broker, payload string) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(payload), &fields); err != nil {
+		log.Printf("⚠️  events listener: bad payload %q: %v", payload, err)
+		return
+	}
+	var typ string
+	json.Unmarshal(fields["type"], &typ)
+	delete(fields, "type")
+	data, _ := json.Marshal(fields)
+	b.Publish(typ, data)
+}
@@ -0,0 +1,119 @@
+// Package filter defines the user list filter AST shared by the query
+// params of GET /users and the ?filter= expression syntax, for example
+//
+//	gender = female AND (age >= 25 OR name ~ 'ali') AND NOT id IN (1, 2, 3)
+//
+// Storage backends compile or evaluate the AST; they never see raw input.
+package filter
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Kind int
+
+const (
+	Int Kind = iota
+	Text
+	Date
+)
+
+// Fields lists every filterable field. age is derived from birthdate.
+var Fields = map[string]Kind{
+	"id":        Int,
+	"name":      Text,
+	"email":     Text,
+	"gender":    Text,
+	"birthdate": Date,
+	"age":       Int,
+}
+
+type Op string
+
+const (
+	Eq       Op = "="
+	Ne       Op = "!="
+	Lt       Op = "<"
+	Le       Op = "<="
+	Gt       Op = ">"
+	Ge       Op = ">="
+	In       Op = "IN"
+	Contains Op = "~" // case-insensitive substring match, text fields only
+)
+
+type Expr interface {
+	isExpr()
+}
+
+type (
+	And  []Expr
+	Or   []Expr
+	Not  struct{ X Expr }
+	Cond struct {
+		Field  string
+		Op     Op
+		Values []string // one value, or several for In; already validated for the field's Kind
+	}
+)
+
+func (And) isExpr()  {}
+func (Or) isExpr()   {}
+func (Not) isExpr()  {}
+func (Cond) isExpr() {}
+
+// FieldError reports a filter on an unknown field or with a bad value.
+type FieldError struct {
+	Field string
+	Msg   string
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("field %q: %s", e.Field, e.Msg)
+}
+
+// NewCond validates field, op and values and builds a condition.
+func NewCond(field string, op Op, values ...string) (Cond, error) {
+	kind, ok := Fields[field]
+	if !ok {
+		return Cond{}, &FieldError{field, "unknown field, allowed: " + strings.Join(FieldNames(), ", ")}
+	}
+	if op == Contains && kind != Text {
+		return Cond{}, &FieldError{field, "~ only applies to text fields"}
+	}
+	if len(values) == 0 || (op != In && len(values) > 1) {
+		return Cond{}, &FieldError{field, "wrong number of values"}
+	}
+	for _, v := range values {
+		if err := checkValue(kind, v); err != nil {
+			return Cond{}, &FieldError{field, err.Error()}
+		}
+	}
+	return Cond{Field: field, Op: op, Values: values}, nil
+}
+
+func checkValue(kind Kind, v string) error {
+	switch kind {
+	case Int:
+		if _, err := strconv.Atoi(v); err != nil {
+			return fmt.Errorf("%q is not an integer", v)
+		}
+	case Date:
+		if _, err := time.Parse("2006-01-02", v); err != nil {
+			return fmt.Errorf("%q is not a YYYY-MM-DD date", v)
+		}
+	}
+	return nil
+}
+
+func FieldNames() []string {
+	names := make([]string, 0, len(Fields))
+	for f := range Fields {
+		names = append(names, f)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,274 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// SyntaxError reports malformed ?filter= input.
+type SyntaxError struct {
+	Pos int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("syntax error at position %d: %s", e.Pos, e.Msg)
+}
+
+// Parse parses the ?filter= expression grammar:
+//
+//	expr  = and { OR and }
+//	and   = unary { AND unary }
+//	unary = NOT unary | "(" expr ")" | cond
+//	cond  = field op value | field [NOT] IN "(" value { "," value } ")"
+//	op    = "=" | "!=" | "<>" | "<" | "<=" | ">" | ">=" | "~"
+//
+// Values are numbers, bare words or 'quoted' / "quoted" strings.
+// Keywords are case-insensitive.
+func Parse(s string) (Expr, error) {
+	toks, err := lex(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks}
+	e, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, &SyntaxError{t.pos, fmt.Sprintf("unexpected %q", t.text)}
+	}
+	return e, nil
+}
+
+type tokKind int
+
+const (
+	tokEOF tokKind = iota
+	tokWord
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+	tokComma
+)
+
+type token struct {
+	kind tokKind
+	text string
+	pos  int
+}
+
+// lex splits s into tokens. It decodes UTF-8, so bare values such as José
+// stay one word; positions are byte offsets.
+func lex(s string) ([]token, error) {
+	var toks []token
+	for i := 0; i < len(s); {
+		c, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case c == utf8.RuneError && size == 1:
+			return nil, &SyntaxError{i, "invalid UTF-8"}
+		case unicode.IsSpace(c):
+			i += size
+		case c == '(':
+			toks = append(toks, token{tokLParen, "(", i})
+			i++
+		case c == ')':
+			toks = append(toks, token{tokRParen, ")", i})
+			i++
+		case c == ',':
+			toks = append(toks, token{tokComma, ",", i})
+			i++
+		case c == '\'' || c == '"':
+			end := strings.IndexByte(s[i+1:], s[i])
+			if end < 0 {
+				return nil, &SyntaxError{i, "unterminated string"}
+			}
+			toks = append(toks, token{tokString, s[i+1 : i+1+end], i})
+			i += end + 2
+		case strings.ContainsRune("=!<>~", c):
+			start := i
+			for i < len(s) && strings.IndexByte("=!<>~", s[i]) >= 0 {
+				i++
+			}
+			toks = append(toks, token{tokOp, s[start:i], start})
+		case isWordChar(c):
+			start := i
+			for i < len(s) {
+				r, n := utf8.DecodeRuneInString(s[i:])
+				if !isWordChar(r) || (r == utf8.RuneError && n == 1) {
+					break
+				}
+				i += n
+			}
+			toks = append(toks, token{tokWord, s[start:i], start})
+		default:
+			return nil, &SyntaxError{i, fmt.Sprintf("unexpected character %q", c)}
+		}
+	}
+	return append(toks, token{tokEOF, "end of input", len(s)}), nil
+}
+
+func isWordChar(c rune) bool {
+	return c == '_' || c == '-' || c == '.' || c == '@' || unicode.IsLetter(c) || unicode.IsDigit(c)
+}
+
+type parser struct {
+	toks []token
+	i    int
+}
+
+func (p *parser) peek() token { return p.toks[p.i] }
+
+func (p *parser) next() token {
+	t := p.toks[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+func (p *parser) keyword(kw string) bool {
+	t := p.peek()
+	if t.kind == tokWord && strings.EqualFold(t.text, kw) {
+		p.i++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expr() (Expr, error) {
+	first, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	or := Or{first}
+	for p.keyword("OR") {
+		e, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		or = append(or, e)
+	}
+	if len(or) == 1 {
+		return first, nil
+	}
+	return or, nil
+}
+
+func (p *parser) and() (Expr, error) {
+	first, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	and := And{first}
+	for p.keyword("AND") {
+		e, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		and = append(and, e)
+	}
+	if len(and) == 1 {
+		return first, nil
+	}
+	return and, nil
+}
+
+func (p *parser) unary() (Expr, error) {
+	if p.keyword("NOT") {
+		e, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return Not{e}, nil
+	}
+	if p.peek().kind == tokLParen {
+		p.next()
+		e, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if t := p.next(); t.kind != tokRParen {
+			return nil, &SyntaxError{t.pos, fmt.Sprintf("expected ')', got %q", t.text)}
+		}
+		return e, nil
+	}
+	return p.cond()
+}
+
+func (p *parser) cond() (Expr, error) {
+	ft := p.next()
+	if ft.kind != tokWord {
+		return nil, &SyntaxError{ft.pos, fmt.Sprintf("expected a field name, got %q", ft.text)}
+	}
+	field := strings.ToLower(ft.text)
+
+	negate := p.keyword("NOT")
+	if p.keyword("IN") {
+		values, err := p.list()
+		if err != nil {
+			return nil, err
+		}
+		c, err := NewCond(field, In, values...)
+		if err != nil {
+			return nil, err
+		}
+		if negate {
+			return Not{c}, nil
+		}
+		return c, nil
+	}
+	if negate {
+		t := p.peek()
+		return nil, &SyntaxError{t.pos, fmt.Sprintf("expected IN after NOT, got %q", t.text)}
+	}
+
+	ot := p.next()
+	var op Op
+	switch ot.text {
+	case "=", "!=", "<", "<=", ">", ">=", "~":
+		op = Op(ot.text)
+	case "<>":
+		op = Ne
+	}
+	if ot.kind != tokOp || op == "" {
+		return nil, &SyntaxError{ot.pos, fmt.Sprintf("expected an operator after %q, got %q", ft.text, ot.text)}
+	}
+	v, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+	return NewCond(field, op, v)
+}
+
+func (p *parser) list() ([]string, error) {
+	if t := p.next(); t.kind != tokLParen {
+		return nil, &SyntaxError{t.pos, fmt.Sprintf("expected '(' after IN, got %q", t.text)}
+	}
+	var values []string
+	for {
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+		t := p.next()
+		if t.kind == tokRParen {
+			return values, nil
+		}
+		if t.kind != tokComma {
+			return nil, &SyntaxError{t.pos, fmt.Sprintf("expected ',' or ')', got %q", t.text)}
+		}
+	}
+}
+
+func (p *parser) value() (string, error) {
+	t := p.next()
+	if t.kind != tokWord && t.kind != tokString {
+		return "", &SyntaxError{t.pos, fmt.Sprintf("expected a value, got %q", t.text)}
+	}
+	return t.text, nil
+}
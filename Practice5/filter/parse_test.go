@@ -0,0 +1,86 @@
+package filter
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Expr
+	}{
+		{"gender = female", Cond{"gender", Eq, []string{"female"}}},
+		{"NAME ~ 'van der'", Cond{"name", Contains, []string{"van der"}}},
+		{"birthdate >= 1995-01-01", Cond{"birthdate", Ge, []string{"1995-01-01"}}},
+		{"id <> 3", Cond{"id", Ne, []string{"3"}}},
+		{"name = José", Cond{"name", Eq, []string{"José"}}},
+		{"name ~ Ærøskøbing", Cond{"name", Contains, []string{"Ærøskøbing"}}},
+		{"id in (1, 2,3)", Cond{"id", In, []string{"1", "2", "3"}}},
+		{"id NOT IN (1)", Not{Cond{"id", In, []string{"1"}}}},
+		{
+			"gender = female AND (age >= 25 OR name ~ \"ali\") AND NOT id = 1",
+			And{
+				Cond{"gender", Eq, []string{"female"}},
+				Or{
+					Cond{"age", Ge, []string{"25"}},
+					Cond{"name", Contains, []string{"ali"}},
+				},
+				Not{Cond{"id", Eq, []string{"1"}}},
+			},
+		},
+		{
+			"id = 1 OR id = 2 AND gender = male",
+			Or{
+				Cond{"id", Eq, []string{"1"}},
+				And{Cond{"id", Eq, []string{"2"}}, Cond{"gender", Eq, []string{"male"}}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := Parse(tt.in)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v; want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		in        string
+		wantField string // "" means a syntax error is expected
+	}{
+		{"salary > 10", "salary"},
+		{"id = abc", "id"},
+		{"age ~ 3", "age"},
+		{"birthdate < 12.03.1995", "birthdate"},
+		{"gender =", ""},
+		{"(id = 1", ""},
+		{"id = 1 gender = male", ""},
+		{"id IN 1, 2", ""},
+		{"name = 'open", ""},
+		{"id NOT = 1", ""},
+		{"name = \xff", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			_, err := Parse(tt.in)
+			var fe *FieldError
+			var se *SyntaxError
+			switch {
+			case tt.wantField != "":
+				if !errors.As(err, &fe) || fe.Field != tt.wantField {
+					t.Errorf("err = %v; want FieldError for %q", err, tt.wantField)
+				}
+			case !errors.As(err, &se):
+				t.Errorf("err = %v; want SyntaxError", err)
+			}
+		})
+	}
+}
@@ -1,5 +1,28 @@
 module practice5
 
-go 1.21
+go 1.22
 
 require github.com/lib/pq v1.10.9
+
+require gopkg.in/yaml.v3 v3.0.1
+
+require github.com/graph-gophers/graphql-go v1.5.0
+
+require github.com/prometheus/client_golang v1.22.0
+
+require github.com/golang-jwt/jwt/v5 v5.3.1
+
+require github.com/swaggo/files/v2 v2.0.2
+
+require (
+	github.com/beorn7/perks v1.0.1 // indirect
+	github.com/cespare/xxhash/v2 v2.3.0 // indirect
+	github.com/kr/text v0.2.0 // indirect
+	github.com/kylelemons/godebug v1.1.0 // indirect
+	github.com/munnerz/goautoneg v0.0.0-20191010083416-a7dc8b61c822 // indirect
+	github.com/prometheus/client_model v0.6.1 // indirect
+	github.com/prometheus/common v0.62.0 // indirect
+	github.com/prometheus/procfs v0.15.1 // indirect
+	golang.org/x/sys v0.30.0 // indirect
+	google.golang.org/protobuf v1.36.5 // indirect
+)
@@ -0,0 +1,175 @@
+package gql
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"practice5/filter"
+	"practice5/migrations"
+	"practice5/models"
+	"practice5/repository"
+)
+
+// countingStore records the batches FriendLists is called with.
+type countingStore struct {
+	repository.UserStore
+	mu      sync.Mutex
+	batches [][]int
+}
+
+func (s *countingStore) FriendLists(ctx context.Context, ids []int) (map[int][]models.User, error) {
+	s.mu.Lock()
+	s.batches = append(s.batches, ids)
+	s.mu.Unlock()
+	return s.UserStore.FriendLists(ctx, ids)
+}
+
+func newTestHandler(t *testing.T) (*Handler, *countingStore) {
+	t.Helper()
+	mem := repository.NewMemoryStore()
+	if err := mem.LoadSeed(migrations.Seed); err != nil {
+		t.Fatal(err)
+	}
+	store := &countingStore{UserStore: mem}
+	return NewHandler(store), store
+}
+
+func exec(t *testing.T, h *Handler, body string) (int, map[string]interface{}) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body)))
+	var resp map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("%v: %s", err, w.Body)
+	}
+	return w.Code, resp
+}
+
+func TestFriendsAreBatchedPerLevel(t *testing.T) {
+	h, store := newTestHandler(t)
+	_, resp := exec(t, h, `{"query": "{ user(id: 3) { name friends { id friendCount friends { id friends { id } } } commonFriends(with: 4) { id } } }"}`)
+	if resp["errors"] != nil {
+		t.Fatalf("errors: %v", resp["errors"])
+	}
+
+	data, _ := json.Marshal(resp["data"])
+	want := `{"user":{"commonFriends":[{"id":"1"},{"id":"2"}],"friends":[{"friendCount":4,"friends":[`
+	if !strings.HasPrefix(string(data), want) {
+		t.Errorf("data = %s\nwant prefix %s", data, want)
+	}
+
+	// One query per level of friends, whatever the number of users on it.
+	// User 4 for commonFriends joins whichever batch runs next, or takes one
+	// of its own when it comes in between two levels.
+	if len(store.batches) > 4 {
+		t.Errorf("FriendLists called %d times (%v); want at most 4", len(store.batches), store.batches)
+	}
+	together := false
+	for _, b := range store.batches {
+		together = together || slices.Contains(b, 1) && slices.Contains(b, 2)
+	}
+	if !together {
+		t.Errorf("batches = %v; want the friends of 3 together", store.batches)
+	}
+}
+
+func TestOnlyReturnedFriendsArePrimed(t *testing.T) {
+	h, store := newTestHandler(t)
+	// User 1 has four friends; only the first is handed out, and only its
+	// friends are fetched next. friendCount primes nobody.
+	_, resp := exec(t, h, `{"query": "{ user(id: 1) { friendCount friends(first: 1) { id friends { id friendCount } } } }"}`)
+	if resp["errors"] != nil {
+		t.Fatalf("errors: %v", resp["errors"])
+	}
+	if want := [][]int{{1}, {2}, {3, 4, 5}}; !reflect.DeepEqual(store.batches, want) {
+		t.Errorf("batches = %v; want %v", store.batches, want)
+	}
+}
+
+func TestUsersQuery(t *testing.T) {
+	h, _ := newTestHandler(t)
+	query := `{"query": "query($after: String) { users(filter: \"gender = female AND age >= 20\", sort: \"-birthdate\", first: 2, after: $after) { nodes { id } pageInfo { hasNextPage endCursor } } }", "variables": {"after": %s}}`
+
+	var ids []interface{}
+	after := "null"
+	for i := 0; i < 20; i++ {
+		_, resp := exec(t, h, strings.Replace(query, "%s", after, 1))
+		if resp["errors"] != nil {
+			t.Fatalf("errors: %v", resp["errors"])
+		}
+		conn := resp["data"].(map[string]interface{})["users"].(map[string]interface{})
+		for _, n := range conn["nodes"].([]interface{}) {
+			ids = append(ids, n.(map[string]interface{})["id"])
+		}
+		info := conn["pageInfo"].(map[string]interface{})
+		if info["hasNextPage"] != true {
+			break
+		}
+		cursor, _ := json.Marshal(info["endCursor"])
+		after = string(cursor)
+	}
+
+	e, err := filter.Parse("gender = female AND age >= 20")
+	if err != nil {
+		t.Fatal(err)
+	}
+	all, err := h.store.GetPaginatedUsers(context.Background(), models.FilterParams{Filter: e, Sort: "-birthdate", Page: 1, PageSize: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want []interface{}
+	for _, u := range all.Data {
+		want = append(want, strconv.Itoa(u.ID))
+	}
+	if len(want) < 3 || !reflect.DeepEqual(ids, want) {
+		t.Errorf("ids across pages = %v; want %v", ids, want)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	h, _ := newTestHandler(t)
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		code   string
+	}{
+		{"bad filter", `{"query": "{ users(filter: \"nope = 1\") { nodes { id } } }"}`, http.StatusOK, "invalid_filter"},
+		{"bad sort", `{"query": "{ users(sort: \"shoe_size\") { nodes { id } } }"}`, http.StatusOK, "invalid_sort"},
+		{"page too large", `{"query": "{ users(first: 1000) { nodes { id } } }"}`, http.StatusOK, "invalid_param"},
+		{"bad id", `{"query": "{ user(id: \"abc\") { id } }"}`, http.StatusOK, "invalid_param"},
+		{"not json", `query { users { nodes { id } } }`, http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, resp := exec(t, h, tt.body)
+			if status != tt.status {
+				t.Errorf("status = %d; want %d", status, tt.status)
+			}
+			errs, _ := resp["errors"].([]interface{})
+			if len(errs) == 0 {
+				t.Fatalf("no errors in %v", resp)
+			}
+			if tt.code == "" {
+				return
+			}
+			ext, _ := errs[0].(map[string]interface{})["extensions"].(map[string]interface{})
+			if ext["code"] != tt.code {
+				t.Errorf("extensions = %v; want code %s", ext, tt.code)
+			}
+		})
+	}
+
+	_, resp := exec(t, h, `{"query": "{ user(id: 99) { id } }"}`)
+	if resp["errors"] != nil || resp["data"].(map[string]interface{})["user"] != nil {
+		t.Errorf("missing user: %v; want null without errors", resp)
+	}
+}
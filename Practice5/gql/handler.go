@@ -0,0 +1,63 @@
+// Package gql serves the users and friendships over GraphQL at /graphql.
+// The schema is in schema.graphql; friend lists at every depth go through
+// a per-request friendLoader.
+package gql
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"net/http"
+
+	graphql "github.com/graph-gophers/graphql-go"
+	gqlerrors "github.com/graph-gophers/graphql-go/errors"
+
+	"practice5/repository"
+)
+
+//go:embed schema.graphql
+var schemaSDL string
+
+const (
+	// maxDepth stops friends { friends { friends ... } } from fanning out
+	// over the whole graph.
+	maxDepth = 6
+	maxBody  = 1 << 20
+)
+
+type Handler struct {
+	schema *graphql.Schema
+	store  repository.UserStore
+}
+
+func NewHandler(store repository.UserStore) *Handler {
+	schema := graphql.MustParseSchema(schemaSDL, &resolver{store: store}, graphql.MaxDepth(maxDepth))
+	return &Handler{schema: schema, store: store}
+}
+
+type request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// POST /graphql
+// {"query": "...", "operationName": "...", "variables": {...}}
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req request
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBody)).Decode(&req); err != nil {
+		writeResponse(w, http.StatusBadRequest, &graphql.Response{
+			Errors: []*gqlerrors.QueryError{{Message: "the body must be a JSON object with a query: " + err.Error()}},
+		})
+		return
+	}
+
+	ctx := context.WithValue(r.Context(), loaderKey{}, newFriendLoader(h.store.FriendLists))
+	writeResponse(w, http.StatusOK, h.schema.Exec(ctx, req.Query, req.OperationName, req.Variables))
+}
+
+func writeResponse(w http.ResponseWriter, status int, resp *graphql.Response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
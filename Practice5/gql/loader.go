@@ -0,0 +1,97 @@
+package gql
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"practice5/models"
+)
+
+// friendLoader batches friend-list lookups within one request. Every user a
+// resolver hands out is primed; the first lookup then fetches the friends
+// of every primed user in one query. A whole level of the result, such as
+// all friends of all friends, therefore costs one query instead of one per
+// user, independently of the order resolvers run in.
+//
+// Resolvers waiting on a batch say how many of the friends they will hand
+// out, and those are primed as the batch completes, before any waiter goes
+// on to the next level. Friends nobody returns, such as those only
+// counted, are never primed.
+type friendLoader struct {
+	fetch func(ctx context.Context, ids []int) (map[int][]models.User, error)
+
+	mu      sync.Mutex
+	pending map[int]bool
+	results map[int]*friendResult
+}
+
+type friendResult struct {
+	done    chan struct{}
+	friends []models.User
+	err     error
+	prime   int // most friends any waiter will hand out
+}
+
+func newFriendLoader(fetch func(ctx context.Context, ids []int) (map[int][]models.User, error)) *friendLoader {
+	return &friendLoader{fetch: fetch, pending: map[int]bool{}, results: map[int]*friendResult{}}
+}
+
+// prime queues users for the next batch.
+func (l *friendLoader) prime(users []models.User) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, u := range users {
+		if l.results[u.ID] == nil {
+			l.pending[u.ID] = true
+		}
+	}
+}
+
+// load returns the friends of id, lowest id first. The caller hands out at
+// most prime of them.
+func (l *friendLoader) load(ctx context.Context, id, prime int) ([]models.User, error) {
+	l.mu.Lock()
+	if r, ok := l.results[id]; ok {
+		r.prime = max(r.prime, prime)
+		l.mu.Unlock()
+		select {
+		case <-r.done:
+			return r.friends, r.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	l.pending[id] = true
+	batch := make([]int, 0, len(l.pending))
+	results := make(map[int]*friendResult, len(l.pending))
+	for k := range l.pending {
+		batch = append(batch, k)
+		results[k] = &friendResult{done: make(chan struct{})}
+		l.results[k] = results[k]
+	}
+	results[id].prime = prime
+	clear(l.pending)
+	l.mu.Unlock()
+
+	sort.Ints(batch)
+	lists, err := l.fetch(ctx, batch)
+	l.mu.Lock()
+	for k, r := range results {
+		r.friends, r.err = lists[k], err
+		if r.friends == nil && err == nil {
+			r.friends = []models.User{}
+		}
+		for _, f := range r.friends[:min(r.prime, len(r.friends))] {
+			if l.results[f.ID] == nil {
+				l.pending[f.ID] = true
+			}
+		}
+	}
+	l.mu.Unlock()
+	for _, r := range results {
+		close(r.done)
+	}
+	return results[id].friends, results[id].err
+}
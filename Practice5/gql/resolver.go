@@ -0,0 +1,246 @@
+package gql
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"strconv"
+
+	graphql "github.com/graph-gophers/graphql-go"
+
+	"practice5/filter"
+	"practice5/models"
+	"practice5/repository"
+)
+
+const (
+	maxPageSize       = 100
+	suggestionSamples = 3
+	maxSuggestions    = 50
+)
+
+type resolver struct {
+	store repository.UserStore
+}
+
+type loaderKey struct{}
+
+func loaderFrom(ctx context.Context) *friendLoader {
+	return ctx.Value(loaderKey{}).(*friendLoader)
+}
+
+// users wraps a list for the schema, priming it for the next friend batch.
+func (r *resolver) users(ctx context.Context, list []models.User) []*userResolver {
+	loaderFrom(ctx).prime(list)
+	out := make([]*userResolver, len(list))
+	for i, u := range list {
+		out[i] = &userResolver{root: r, u: u}
+	}
+	return out
+}
+
+func (r *resolver) Users(ctx context.Context, args struct {
+	Filter *string
+	Sort   *string
+	First  int32
+	After  *string
+}) (*connectionResolver, error) {
+	if args.First < 1 || args.First > maxPageSize {
+		return nil, &Error{Code: "invalid_param", Message: "first must be between 1 and " + strconv.Itoa(maxPageSize)}
+	}
+	after := ""
+	if args.After != nil {
+		after = *args.After
+	}
+	p := models.FilterParams{PageSize: int(args.First), Cursor: &after}
+	if args.Sort != nil {
+		p.Sort = *args.Sort
+	}
+	if args.Filter != nil && *args.Filter != "" {
+		e, err := filter.Parse(*args.Filter)
+		if err != nil {
+			return nil, toError(err)
+		}
+		p.Filter = e
+	}
+
+	page, err := r.store.GetPaginatedUsers(ctx, p)
+	if err != nil {
+		return nil, toError(err)
+	}
+	return &connectionResolver{nodes: r.users(ctx, page.Data), next: page.NextCursor}, nil
+}
+
+func (r *resolver) User(ctx context.Context, args struct{ ID graphql.ID }) (*userResolver, error) {
+	id, err := parseID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+	u, err := r.store.GetUserByID(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, toError(err)
+	}
+	return r.users(ctx, []models.User{u})[0], nil
+}
+
+type connectionResolver struct {
+	nodes []*userResolver
+	next  string
+}
+
+func (c *connectionResolver) Nodes() []*userResolver { return c.nodes }
+
+func (c *connectionResolver) PageInfo() *pageInfoResolver { return &pageInfoResolver{c.next} }
+
+type pageInfoResolver struct{ next string }
+
+func (p *pageInfoResolver) HasNextPage() bool { return p.next != "" }
+
+func (p *pageInfoResolver) EndCursor() *string {
+	if p.next == "" {
+		return nil
+	}
+	return &p.next
+}
+
+type userResolver struct {
+	root *resolver
+	u    models.User
+}
+
+func (u *userResolver) ID() graphql.ID    { return graphql.ID(strconv.Itoa(u.u.ID)) }
+func (u *userResolver) Name() string      { return u.u.Name }
+func (u *userResolver) Email() string     { return u.u.Email }
+func (u *userResolver) Gender() string    { return u.u.Gender }
+func (u *userResolver) Birthdate() string { return u.u.Birthdate.Format("2006-01-02") }
+
+func (u *userResolver) FriendCount(ctx context.Context) (int32, error) {
+	friends, err := loaderFrom(ctx).load(ctx, u.u.ID, 0)
+	if err != nil {
+		return 0, toError(err)
+	}
+	return int32(len(friends)), nil
+}
+
+func (u *userResolver) Friends(ctx context.Context, args struct{ First *int32 }) ([]*userResolver, error) {
+	first := math.MaxInt
+	if args.First != nil {
+		if *args.First < 0 {
+			return nil, &Error{Code: "invalid_param", Message: "first must not be negative"}
+		}
+		first = int(*args.First)
+	}
+	friends, err := loaderFrom(ctx).load(ctx, u.u.ID, first)
+	if err != nil {
+		return nil, toError(err)
+	}
+	return u.root.users(ctx, friends[:min(first, len(friends))]), nil
+}
+
+// CommonFriends intersects two friend lists from the loader, so it is
+// batched along with friends.
+func (u *userResolver) CommonFriends(ctx context.Context, args struct{ With graphql.ID }) ([]*userResolver, error) {
+	with, err := parseID(args.With)
+	if err != nil {
+		return nil, err
+	}
+	l := loaderFrom(ctx)
+	l.prime([]models.User{{ID: with}}) // fetched along with mine
+	mine, err := l.load(ctx, u.u.ID, 0)
+	if err != nil {
+		return nil, toError(err)
+	}
+	theirs, err := l.load(ctx, with, 0)
+	if err != nil {
+		return nil, toError(err)
+	}
+	shared := map[int]bool{}
+	for _, f := range theirs {
+		shared[f.ID] = true
+	}
+	common := []models.User{}
+	for _, f := range mine {
+		if shared[f.ID] {
+			common = append(common, f)
+		}
+	}
+	return u.root.users(ctx, common), nil
+}
+
+// Suggestions runs one ranking query per user it is asked for; it is meant
+// for the profile at the top of a query, not for every friend in a list.
+func (u *userResolver) Suggestions(ctx context.Context, args struct{ First int32 }) ([]*suggestionResolver, error) {
+	if args.First < 1 || args.First > maxSuggestions {
+		return nil, &Error{Code: "invalid_param", Message: "first must be between 1 and " + strconv.Itoa(maxSuggestions)}
+	}
+	suggestions, err := u.root.store.GetFriendSuggestions(ctx, u.u.ID, int(args.First), suggestionSamples)
+	if err != nil {
+		return nil, toError(err)
+	}
+	users := make([]models.User, len(suggestions))
+	for i, s := range suggestions {
+		users[i] = s.User
+	}
+	wrapped := u.root.users(ctx, users)
+	out := make([]*suggestionResolver, len(suggestions))
+	for i, s := range suggestions {
+		out[i] = &suggestionResolver{user: wrapped[i], s: s}
+	}
+	return out, nil
+}
+
+type suggestionResolver struct {
+	user *userResolver
+	s    models.FriendSuggestion
+}
+
+func (s *suggestionResolver) User() *userResolver     { return s.user }
+func (s *suggestionResolver) MutualCount() int32      { return int32(s.s.MutualCount) }
+func (s *suggestionResolver) MutualFriends() []string { return s.s.MutualFriends }
+
+func parseID(id graphql.ID) (int, error) {
+	n, err := strconv.Atoi(string(id))
+	if err != nil || n < 1 {
+		return 0, &Error{Code: "invalid_param", Message: "id " + strconv.Quote(string(id)) + " is not a positive integer"}
+	}
+	return n, nil
+}
+
+// Error is a GraphQL error carrying the same stable code the REST API uses
+// in its problem responses, under extensions.code.
+type Error struct {
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string { return e.Message }
+
+func (e *Error) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": e.Code}
+}
+
+// toError turns store and filter errors into client-safe errors. Anything
+// unexpected is logged and reported without its driver text.
+func toError(err error) error {
+	var (
+		repoErr *repository.Error
+		syntax  *filter.SyntaxError
+		fieldE  *filter.FieldError
+	)
+	switch {
+	case errors.As(err, &syntax), errors.As(err, &fieldE):
+		return &Error{Code: "invalid_filter", Message: err.Error()}
+	case errors.As(err, &repoErr):
+		return &Error{Code: repoErr.Code, Message: repoErr.Message}
+	case errors.Is(err, context.DeadlineExceeded):
+		return &Error{Code: "timeout", Message: repository.ErrTimeout.Message}
+	case errors.Is(err, context.Canceled):
+		return &Error{Code: "cancelled", Message: "the request was cancelled"}
+	}
+	log.Printf("❌ graphql resolver failed: %v", err)
+	return &Error{Code: "internal", Message: "something went wrong"}
+}
@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"net/http"
+
+	"practice5/analytics"
+	"practice5/repository"
+)
+
+// Analytics serves the admin friendship-graph reports. Figures come from a
+// periodically refreshed snapshot, so they may lag recent writes by up to
+// the refresh interval; computed_at says when the snapshot was taken.
+type Analytics struct {
+	svc *analytics.Service
+}
+
+func NewAnalytics(svc *analytics.Service) *Analytics {
+	return &Analytics{svc: svc}
+}
+
+func (a *Analytics) report(w http.ResponseWriter, r *http.Request) (*analytics.Report, bool) {
+	rep, err := a.svc.Report(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return nil, false
+	}
+	return rep, true
+}
+
+// GET /admin/graph
+// Counts, component sizes and averages for the whole graph.
+func (a *Analytics) Summary(w http.ResponseWriter, r *http.Request) {
+	if rep, ok := a.report(w, r); ok {
+		writeJSON(w, http.StatusOK, rep.Summary)
+	}
+}
+
+// GET /admin/graph/components?limit=20&min_size=2
+// Connected components, largest first. Isolated users are components of
+// size 1; min_size=2 leaves them out.
+func (a *Analytics) Components(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	limit, err := rangeParam(q, "limit", 20, 1, 1000)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	minSize, err := rangeParam(q, "min_size", 1, 1, 0)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	rep, ok := a.report(w, r)
+	if !ok {
+		return
+	}
+
+	comps := []analytics.Component{}
+	for _, c := range rep.Components {
+		if c.Size < minSize || len(comps) == limit {
+			break
+		}
+		comps = append(comps, c)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"computed_at": rep.Summary.ComputedAt,
+		"total":       rep.Summary.Components,
+		"components":  comps,
+	})
+}
+
+// GET /admin/graph/top-users?limit=10
+// The users with the most friends, with their clustering coefficients.
+func (a *Analytics) TopUsers(w http.ResponseWriter, r *http.Request) {
+	limit, err := rangeParam(r.URL.Query(), "limit", 10, 1, 1000)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if rep, ok := a.report(w, r); ok {
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"computed_at": rep.Summary.ComputedAt,
+			"users":       rep.TopUsers(limit),
+		})
+	}
+}
+
+// GET /admin/graph/isolated
+// Ids of users without any friends.
+func (a *Analytics) Isolated(w http.ResponseWriter, r *http.Request) {
+	if rep, ok := a.report(w, r); ok {
+		ids := rep.Isolated()
+		if ids == nil {
+			ids = []int{}
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"computed_at": rep.Summary.ComputedAt,
+			"user_ids":    ids,
+		})
+	}
+}
+
+// GET /admin/graph/users/{id}
+// Degree, clustering coefficient and component of one user. Users created
+// after the snapshot are not found until the next refresh.
+func (a *Analytics) User(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	rep, ok := a.report(w, r)
+	if !ok {
+		return
+	}
+	stats, found := rep.User(id)
+	if !found {
+		writeError(w, r, repository.ErrNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
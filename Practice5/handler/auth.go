@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"practice5/auth"
+)
+
+// RoleAdmin is the role write and /admin endpoints require.
+const RoleAdmin = "admin"
+
+// RequireToken lets a request through only with a valid bearer token and,
+// when role is not empty, that role; the claims are then available through
+// auth.FromContext. A nil verifier turns authentication off.
+func RequireToken(v *auth.Verifier, role string, next http.HandlerFunc) http.HandlerFunc {
+	if v == nil {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="practice5"`)
+			writeProblem(w, r, http.StatusUnauthorized, "unauthorized", "a bearer token is required")
+			return
+		}
+		claims, err := v.Verify(strings.TrimSpace(token))
+		if err != nil {
+			detail := "the bearer token is invalid"
+			if errors.Is(err, auth.ErrExpired) {
+				detail = "the bearer token has expired"
+			}
+			w.Header().Set("WWW-Authenticate", `Bearer realm="practice5", error="invalid_token"`)
+			writeProblem(w, r, http.StatusUnauthorized, "invalid_token", detail)
+			return
+		}
+		if role != "" && claims.Role != role {
+			writeProblem(w, r, http.StatusForbidden, "forbidden", "this endpoint requires role "+role)
+			return
+		}
+		next(w, r.WithContext(auth.NewContext(r.Context(), claims)))
+	}
+}
+
+// RequireAdmin guards the /admin routes. With a verifier they need a token
+// with role admin, as RequireToken checks. Without one there is nobody to
+// check, so they answer 403 unless open says the deployment keeps them
+// private some other way.
+func RequireAdmin(v *auth.Verifier, open bool, next http.HandlerFunc) http.HandlerFunc {
+	if v != nil {
+		return RequireToken(v, RoleAdmin, next)
+	}
+	if open {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeProblem(w, r, http.StatusForbidden, "forbidden", "admin endpoints are disabled without authentication; configure JWT or start with -admin-open")
+	}
+}
+
+// isAdmin reports whether r may use admin-only options of an open route.
+// Without a verifier no claims are attached and everyone may.
+func isAdmin(r *http.Request) bool {
+	claims, ok := auth.FromContext(r.Context())
+	return !ok || claims.Role == RoleAdmin
+}
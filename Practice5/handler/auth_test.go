@@ -0,0 +1,166 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"practice5/auth"
+	"practice5/migrations"
+	"practice5/models"
+	"practice5/repository"
+)
+
+func hs256Token(secret, role string, exp time.Time) string {
+	token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": "u1", "role": role, "exp": exp.Unix(),
+	}).SignedString([]byte(secret))
+	return token
+}
+
+func TestRequireToken(t *testing.T) {
+	v, err := auth.NewVerifier([]byte("s3cret"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var gotRole string
+	next := func(w http.ResponseWriter, r *http.Request) {
+		c, _ := auth.FromContext(r.Context())
+		gotRole = c.Role
+	}
+	hour := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name     string
+		verifier *auth.Verifier
+		role     string
+		header   string
+		want     int
+	}{
+		{"auth off", nil, RoleAdmin, "", http.StatusOK},
+		{"no token", v, "", "", http.StatusUnauthorized},
+		{"not bearer", v, "", "Basic dTpw", http.StatusUnauthorized},
+		{"bad signature", v, "", "Bearer " + hs256Token("other", "user", hour), http.StatusUnauthorized},
+		{"expired", v, "", "Bearer " + hs256Token("s3cret", "user", time.Now().Add(-time.Hour)), http.StatusUnauthorized},
+		{"any role for reads", v, "", "Bearer " + hs256Token("s3cret", "user", hour), http.StatusOK},
+		{"user on admin route", v, RoleAdmin, "Bearer " + hs256Token("s3cret", "user", hour), http.StatusForbidden},
+		{"admin on admin route", v, RoleAdmin, "Bearer " + hs256Token("s3cret", "admin", hour), http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/users", nil)
+			if tt.header != "" {
+				r.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+			RequireToken(tt.verifier, tt.role, next)(w, r)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d; want %d (%s)", w.Code, tt.want, w.Body)
+			}
+			if w.Code == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+				t.Error("401 without a WWW-Authenticate header")
+			}
+		})
+	}
+	if gotRole != RoleAdmin {
+		t.Errorf("claims role seen by the handler = %q; want admin", gotRole)
+	}
+}
+
+func TestRequireAdmin(t *testing.T) {
+	v, err := auth.NewVerifier([]byte("s3cret"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+	hour := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name     string
+		verifier *auth.Verifier
+		open     bool
+		token    string
+		want     int
+	}{
+		{"auth off, closed by default", nil, false, "", http.StatusForbidden},
+		{"auth off, opened", nil, true, "", http.StatusOK},
+		{"no token", v, true, "", http.StatusUnauthorized},
+		{"user token", v, true, hs256Token("s3cret", "user", hour), http.StatusForbidden},
+		{"admin token", v, false, hs256Token("s3cret", RoleAdmin, hour), http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/admin/graph", nil)
+			if tt.token != "" {
+				r.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			RequireAdmin(tt.verifier, tt.open, ok)(w, r)
+			if w.Code != tt.want {
+				t.Errorf("status = %d; want %d (%s)", w.Code, tt.want, w.Body)
+			}
+		})
+	}
+}
+
+func TestIncludeDeletedIsAdminOnly(t *testing.T) {
+	store := repository.NewMemoryStore()
+	if err := store.LoadSeed(migrations.Seed); err != nil {
+		t.Fatal(err)
+	}
+	h := New(store)
+	if err := store.DeleteUser(context.Background(), 3); err != nil {
+		t.Fatal(err)
+	}
+	v, err := auth.NewVerifier([]byte("s3cret"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hour := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name     string
+		verifier *auth.Verifier
+		role     string
+		target   string
+		want     int
+	}{
+		{"hidden by default", v, "user", "/users?id=3", http.StatusOK},
+		{"user asks for deleted", v, "user", "/users?id=3&include_deleted=true", http.StatusForbidden},
+		{"user on common friends", v, "user", "/users/common-friends?user1=1&user2=2&include_deleted=true", http.StatusForbidden},
+		{"admin asks for deleted", v, RoleAdmin, "/users?id=3&include_deleted=true", http.StatusOK},
+		{"auth off", nil, "", "/users?id=3&include_deleted=1", http.StatusOK},
+		{"not a boolean", v, RoleAdmin, "/users?include_deleted=yes", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			r.Header.Set("Authorization", "Bearer "+hs256Token("s3cret", tt.role, hour))
+			fn := h.GetUsers
+			if strings.HasPrefix(tt.target, "/users/common-friends") {
+				fn = h.GetCommonFriends
+			}
+			w := httptest.NewRecorder()
+			RequireToken(tt.verifier, "", fn)(w, r)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d; want %d (%s)", w.Code, tt.want, w.Body)
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+			var page models.PaginatedResponse
+			if err := json.NewDecoder(w.Body).Decode(&page); err != nil {
+				t.Fatal(err)
+			}
+			if wantRows := strings.Contains(tt.target, "include_deleted"); (len(page.Data) == 1) != wantRows {
+				t.Errorf("got %d users", len(page.Data))
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package handler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"practice5/filter"
+	"practice5/repository"
+)
+
+// statusClientClosedRequest is nginx's code for a client that went away
+// before the response was ready; nobody reads it, but it shows up in logs.
+const statusClientClosedRequest = 499
+
+// writeSlack is how long a response may take to write after its query
+// budget runs out.
+const writeSlack = 10 * time.Second
+
+// WithTimeout bounds the request context, and therefore every query the
+// handler runs, by d. Queries still running when it fires are cancelled and
+// the client gets a 503 from writeError. The connection's read and write
+// deadlines follow d, so routes with a long budget outlive the server-wide
+// ReadTimeout and WriteTimeout: an import may upload its body for as long
+// as its budget lasts, an export may stream its response.
+func WithTimeout(d time.Duration, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), d)
+		defer cancel()
+		// Not every ResponseWriter supports deadlines (httptest's doesn't);
+		// the server-wide timeouts apply then.
+		rc := http.NewResponseController(w)
+		rc.SetReadDeadline(time.Now().Add(d))
+		rc.SetWriteDeadline(time.Now().Add(d + writeSlack))
+		next(w, r.WithContext(ctx))
+	}
+}
+
+type requestIDKey struct{}
+
+// RequestID tags every request with the caller's X-Request-ID, or a fresh
+// one, echoes it in the response and makes it available to error responses
+// and logs.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if id == "" || len(id) > 128 {
+			var b [8]byte
+			rand.Read(b[:])
+			id = hex.EncodeToString(b[:])
+		}
+		w.Header().Set("X-Request-ID", id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+	})
+}
+
+func requestID(r *http.Request) string {
+	id, _ := r.Context().Value(requestIDKey{}).(string)
+	return id
+}
+
+// problem is an RFC 7807 application/problem+json body. Code is stable and
+// meant for programs; Title and Detail are for people and may change.
+type problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      string       `json:"code"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []fieldError `json:"errors,omitempty"`
+}
+
+func writeProblem(w http.ResponseWriter, r *http.Request, status int, code, detail string, fields ...fieldError) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(problem{
+		Type:      "urn:practice5:problem:" + code,
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    detail,
+		Instance:  r.URL.Path,
+		Code:      code,
+		RequestID: requestID(r),
+		Errors:    fields,
+	})
+}
+
+func (e fieldError) Error() string { return e.Field + ": " + e.Message }
+
+// badParam reports an invalid query or path parameter.
+func badParam(field, format string, args ...interface{}) error {
+	return fieldError{field, fmt.Sprintf(format, args...)}
+}
+
+// validationErrors reports every invalid field of a request body.
+type validationErrors []fieldError
+
+func (errs validationErrors) Error() string { return fmt.Sprintf("%d invalid fields", len(errs)) }
+
+// writeError is the single place errors become responses. Everything the
+// client sees is either our own wording or a stable code; driver errors are
+// only logged.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	var (
+		fe      fieldError
+		invalid validationErrors
+		repoErr *repository.Error
+		syntax  *filter.SyntaxError
+		fieldE  *filter.FieldError
+	)
+	switch {
+	case errors.As(err, &invalid):
+		writeProblem(w, r, http.StatusBadRequest, "validation_failed", "the request body has invalid fields", invalid...)
+	case errors.As(err, &fe):
+		writeProblem(w, r, http.StatusBadRequest, "invalid_param", fe.Error(), fe)
+	case errors.As(err, &syntax):
+		writeProblem(w, r, http.StatusBadRequest, "invalid_filter", syntax.Error(), fieldError{"filter", syntax.Error()})
+	case errors.As(err, &fieldE):
+		writeProblem(w, r, http.StatusBadRequest, "invalid_filter", fieldE.Error(), fieldError{fieldE.Field, fieldE.Msg})
+
+	case errors.As(err, &repoErr) && repoErr.Kind != repository.KindTimeout:
+		var fields []fieldError
+		if repoErr.Field != "" {
+			fields = append(fields, fieldError{repoErr.Field, repoErr.Message})
+		}
+		writeProblem(w, r, kindStatus[repoErr.Kind], repoErr.Code, repoErr.Message, fields...)
+
+	// The driver reports a cancelled query in its own words, so the request
+	// context decides whether a failure was a timeout or a disconnect.
+	case isTimeout(r, err):
+		log.Printf("⏱️  [%s] %s %s timed out: %v", requestID(r), r.Method, r.URL.Path, err)
+		w.Header().Set("Retry-After", "1")
+		writeProblem(w, r, http.StatusServiceUnavailable, "timeout", repository.ErrTimeout.Message)
+	case isClientGone(r, err):
+		log.Printf("ℹ️  [%s] %s %s cancelled by client", requestID(r), r.Method, r.URL.Path)
+		w.WriteHeader(statusClientClosedRequest)
+
+	default:
+		log.Printf("❌ [%s] %s %s failed: %v", requestID(r), r.Method, r.URL.Path, err)
+		writeProblem(w, r, http.StatusInternalServerError, "internal", "something went wrong; quote the request_id when reporting it")
+	}
+}
+
+var kindStatus = map[repository.Kind]int{
+	repository.KindInternal: http.StatusInternalServerError,
+	repository.KindNotFound: http.StatusNotFound,
+	repository.KindConflict: http.StatusConflict,
+	repository.KindInvalid:  http.StatusBadRequest,
+	repository.KindTimeout:  http.StatusServiceUnavailable,
+}
+
+func isTimeout(r *http.Request, err error) bool {
+	return errors.Is(err, repository.ErrTimeout) || errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(r.Context().Err(), context.DeadlineExceeded)
+}
+
+func isClientGone(r *http.Request, err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(r.Context().Err(), context.Canceled)
+}
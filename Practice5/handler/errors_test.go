@@ -0,0 +1,161 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"practice5/filter"
+	"practice5/models"
+	"practice5/repository"
+)
+
+func TestWriteError(t *testing.T) {
+	expired := func() context.Context {
+		ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+		cancel()
+		return ctx
+	}
+	cancelled := func() context.Context {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		return ctx
+	}
+	// lib/pq reports a cancelled query as a server error, not ctx.Err().
+	driverErr := errors.New("pq: canceling statement due to user request")
+
+	_, filterErr := filter.Parse("age >")
+
+	tests := []struct {
+		name     string
+		ctx      func() context.Context
+		err      error
+		want     int
+		wantCode string
+	}{
+		{"not found", context.Background, repository.ErrNotFound, http.StatusNotFound, "user_not_found"},
+		{"conflict", context.Background, repository.ErrEmailTaken, http.StatusConflict, "email_taken"},
+		{"invalid sort", context.Background, repository.ErrInvalidSort, http.StatusBadRequest, "invalid_sort"},
+		{"bad param", context.Background, badParam("page", "must be at least 1"), http.StatusBadRequest, "invalid_param"},
+		{"bad filter", context.Background, filterErr, http.StatusBadRequest, "invalid_filter"},
+		{"invalid body", context.Background, validationErrors{{"name", "is required"}}, http.StatusBadRequest, "validation_failed"},
+		{"repo timeout", context.Background, repository.ErrTimeout, http.StatusServiceUnavailable, "timeout"},
+		{"deadline error", context.Background, context.DeadlineExceeded, http.StatusServiceUnavailable, "timeout"},
+		{"driver error after deadline", expired, driverErr, http.StatusServiceUnavailable, "timeout"},
+		{"driver error after disconnect", cancelled, driverErr, statusClientClosedRequest, ""},
+		{"real failure", context.Background, errors.New("pq: relation does not exist"), http.StatusInternalServerError, "internal"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/users", nil).WithContext(tt.ctx())
+			w := httptest.NewRecorder()
+			RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				writeError(w, r, tt.err)
+			})).ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Errorf("status = %d; want %d", w.Code, tt.want)
+			}
+			if tt.wantCode == "" {
+				return
+			}
+			if ct := w.Header().Get("Content-Type"); ct != "application/problem+json" {
+				t.Errorf("Content-Type = %q", ct)
+			}
+			var p problem
+			if err := json.NewDecoder(w.Body).Decode(&p); err != nil {
+				t.Fatal(err)
+			}
+			if p.Code != tt.wantCode || p.Status != tt.want || p.RequestID == "" || p.RequestID != w.Header().Get("X-Request-ID") {
+				t.Errorf("problem = %+v; want code %q", p, tt.wantCode)
+			}
+			if strings.Contains(p.Detail, "pq:") {
+				t.Errorf("detail leaks the driver error: %q", p.Detail)
+			}
+		})
+	}
+}
+
+func TestParseFilterParamsRejectsBadValues(t *testing.T) {
+	for _, raw := range []string{"page=two", "page=0", "page=100001", "page_size=-1", "page_size=101", "id=1,x", "age_min=old", "birthdate=12.03.1995", "salary=1", "page=1&gendr=male"} {
+		q, _ := url.ParseQuery(raw)
+		var fe fieldError
+		if _, err := parseFilterParams(q); !errors.As(err, &fe) {
+			t.Errorf("parseFilterParams(%q) error = %v; want a fieldError", raw, err)
+		}
+	}
+}
+
+func TestParseFilterParamsAcceptsRouteParams(t *testing.T) {
+	q, _ := url.ParseQuery("format=csv&gender=male&page=2")
+	if _, err := parseFilterParams(q, "format"); err != nil {
+		t.Errorf("parseFilterParams with extra format: %v", err)
+	}
+	var fe fieldError
+	if _, err := parseFilterParams(q); !errors.As(err, &fe) || fe.Field != "format" {
+		t.Errorf("parseFilterParams without extras error = %v; want a fieldError for format", err)
+	}
+}
+
+// slowStore answers GetCommonFriends only once the request is cancelled,
+// the way a query killed by its statement timeout does.
+type slowStore struct{ repository.UserStore }
+
+func (slowStore) DataVersion(context.Context) (int64, error) { return 1, nil }
+
+func (slowStore) GetCommonFriends(ctx context.Context, _, _ int, _ bool) ([]models.User, error) {
+	<-ctx.Done()
+	return nil, errors.New("pq: canceling statement due to user request")
+}
+
+func TestCommonFriendsTimeoutIs503(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/users/common-friends?user1=1&user2=2", nil)
+	w := httptest.NewRecorder()
+	WithTimeout(10*time.Millisecond, New(slowStore{}).GetCommonFriends).ServeHTTP(w, r)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d; want 503", w.Code)
+	}
+	var p problem
+	if err := json.NewDecoder(w.Body).Decode(&p); err != nil || p.Code != "timeout" {
+		t.Errorf("problem = %+v (%v); want code timeout", p, err)
+	}
+}
+
+func TestWithTimeoutExtendsReadDeadline(t *testing.T) {
+	srv := httptest.NewUnstartedServer(WithTimeout(time.Second, func(w http.ResponseWriter, r *http.Request) {
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Write(b)
+	}))
+	srv.Config.ReadTimeout = 50 * time.Millisecond
+	srv.Start()
+	defer srv.Close()
+
+	// The body trickles in for longer than the server-wide ReadTimeout.
+	pr, pw := io.Pipe()
+	go func() {
+		for i := 0; i < 4; i++ {
+			time.Sleep(40 * time.Millisecond)
+			pw.Write([]byte("x"))
+		}
+		pw.Close()
+	}()
+	resp, err := http.Post(srv.URL, "text/plain", pr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(body) != "xxxx" {
+		t.Errorf("got %d %q; want 200 \"xxxx\"", resp.StatusCode, body)
+	}
+}
@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"practice5/migrations"
+	"practice5/models"
+	"practice5/repository"
+)
+
+func TestConditionalGet(t *testing.T) {
+	store := repository.NewMemoryStore()
+	if err := store.LoadSeed(migrations.Seed); err != nil {
+		t.Fatal(err)
+	}
+	h := New(store)
+
+	get := func(fn http.HandlerFunc, target, ifNoneMatch string) *httptest.ResponseRecorder {
+		t.Helper()
+		r := httptest.NewRequest(http.MethodGet, target, nil)
+		if ifNoneMatch != "" {
+			r.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		fn(w, r)
+		return w
+	}
+
+	first := get(h.GetUsers, "/users?gender=female&page_size=3", "")
+	tag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || tag == "" {
+		t.Fatalf("first request: status %d, ETag %q", first.Code, tag)
+	}
+
+	if w := get(h.GetUsers, "/users?page_size=3&gender=female", tag); w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Errorf("same query, reordered: status %d, %d body bytes; want 304 and none", w.Code, w.Body.Len())
+	}
+	if w := get(h.GetUsers, "/users?gender=female&page_size=3", `"other", `+tag); w.Code != http.StatusNotModified {
+		t.Errorf("tag in a list: status %d; want 304", w.Code)
+	}
+	if w := get(h.GetUsers, "/users?gender=male&page_size=3", tag); w.Code != http.StatusOK {
+		t.Errorf("other filter: status %d; want 200", w.Code)
+	}
+
+	if _, err := store.UpdateUser(context.Background(), 1, models.UserInput{Name: strPtr("Alice J.")}); err != nil {
+		t.Fatal(err)
+	}
+	w := get(h.GetUsers, "/users?gender=female&page_size=3", tag)
+	if w.Code != http.StatusOK || w.Header().Get("ETag") == tag {
+		t.Errorf("after a write: status %d, ETag %q; want 200 and a new tag", w.Code, w.Header().Get("ETag"))
+	}
+
+	cf := get(h.GetCommonFriends, "/users/common-friends?user1=1&user2=2", "")
+	if w := get(h.GetCommonFriends, "/users/common-friends?user1=1&user2=2", cf.Header().Get("ETag")); w.Code != http.StatusNotModified {
+		t.Errorf("common friends revalidation: status %d; want 304", w.Code)
+	}
+
+	// Bad params are reported even when the client's tag would match.
+	for _, bad := range []struct {
+		fn     http.HandlerFunc
+		target string
+	}{
+		{h.GetUsers, "/users?filter=age+%3E"},
+		{h.GetUsers, "/users?order_by=salary"},
+		{h.GetUsers, "/users?fields=salary"},
+		{h.GetCommonFriends, "/users/common-friends?user1=1&user2=1"},
+	} {
+		if w := get(bad.fn, bad.target, "*"); w.Code != http.StatusBadRequest {
+			t.Errorf("%s with If-None-Match *: status %d; want 400", bad.target, w.Code)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"practice5/events"
+)
+
+const (
+	// heartbeatInterval keeps proxies from closing an idle stream.
+	heartbeatInterval = 15 * time.Second
+	// eventWriteTimeout bounds each write: a client that does not read is
+	// cut off once its TCP buffers are full instead of tying up the
+	// handler.
+	eventWriteTimeout = 10 * time.Second
+	retryMillis       = 3000
+)
+
+// Events streams a Broker as Server-Sent Events.
+type Events struct {
+	broker *events.Broker
+}
+
+func NewEvents(b *events.Broker) *Events {
+	return &Events{broker: b}
+}
+
+// GET /events
+// Streams user.created, user.updated, friendship.added and
+// friendship.removed. A reconnecting client (EventSource sends
+// Last-Event-ID by itself) first gets the buffered events it missed, or a
+// stream.reset event if too many happened meanwhile.
+func (e *Events) Stream(w http.ResponseWriter, r *http.Request) {
+	lastID, resume := uint64(0), false
+	if v := r.Header.Get("Last-Event-ID"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			writeError(w, r, badParam("Last-Event-ID", "%q is not an event id", v))
+			return
+		}
+		lastID, resume = id, true
+	}
+
+	rc := http.NewResponseController(w)
+	sub, replay := e.broker.Subscribe(lastID, resume)
+	defer e.broker.Unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // nginx would buffer the stream otherwise
+	w.WriteHeader(http.StatusOK)
+
+	write := func(format string, args ...interface{}) bool {
+		rc.SetWriteDeadline(time.Now().Add(eventWriteTimeout))
+		if _, err := fmt.Fprintf(w, format, args...); err != nil {
+			return false
+		}
+		return rc.Flush() == nil
+	}
+	send := func(ev events.Event) bool {
+		return write("id: %d\nevent: %s\ndata: %s\n\n", ev.ID, ev.Type, ev.Data)
+	}
+
+	if !write("retry: %d\n\n", retryMillis) {
+		return
+	}
+	for _, ev := range replay {
+		if !send(ev) {
+			return
+		}
+	}
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case ev := <-sub.C:
+			if !send(ev) {
+				return
+			}
+		case <-heartbeat.C:
+			if !write(": ping\n\n") {
+				return
+			}
+		case <-sub.Done:
+			if sub.Dropped() {
+				log.Printf("⚠️  [%s] /events client fell behind and was disconnected", requestID(r))
+			}
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
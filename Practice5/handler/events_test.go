@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"practice5/events"
+)
+
+func TestEventStream(t *testing.T) {
+	b := events.NewBroker(10, 4)
+	srv := httptest.NewServer(http.HandlerFunc(NewEvents(b).Stream))
+	defer srv.Close()
+
+	missed := b.Publish(events.TypeUserCreated, json.RawMessage(`{"user":{"id":1}}`))
+	before := missed.ID - 1
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	req.Header.Set("Last-Event-ID", strconv.FormatUint(before, 10))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+
+	lines := bufio.NewScanner(resp.Body)
+	next := func() []string {
+		t.Helper()
+		var block []string
+		for lines.Scan() {
+			if lines.Text() == "" {
+				if len(block) > 0 && !strings.HasPrefix(block[0], "retry:") {
+					return block
+				}
+				block = nil
+				continue
+			}
+			block = append(block, lines.Text())
+		}
+		t.Fatalf("stream ended: %v", lines.Err())
+		return nil
+	}
+
+	want := []string{"id: " + strconv.FormatUint(missed.ID, 10), "event: user.created", `data: {"user":{"id":1}}`}
+	if got := next(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("replayed %q; want %q", got, want)
+	}
+
+	// Headers are only sent after Subscribe, so this one arrives live.
+	live := b.Publish(events.TypeFriendshipRemoved, json.RawMessage(`{"user_id":1,"friend_id":2}`))
+	if got := next(); got[0] != "id: "+strconv.FormatUint(live.ID, 10) || got[1] != "event: friendship.removed" {
+		t.Fatalf("live event %q", got)
+	}
+
+	b.Close()
+	done := make(chan struct{})
+	go func() {
+		for lines.Scan() {
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream stayed open after the broker closed")
+	}
+}
+
+func TestEventStreamBadLastEventID(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/events", nil)
+	r.Header.Set("Last-Event-ID", "abc")
+	w := httptest.NewRecorder()
+	NewEvents(events.NewBroker(10, 4)).Stream(w, r)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status %d; want 400", w.Code)
+	}
+}
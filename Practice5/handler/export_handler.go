@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"practice5/models"
+)
+
+// GET /users/export?format=csv|ndjson
+// Accepts the same filter and sort params as GET /users and streams every
+// matching row; page, page_size and cursor are ignored.
+func (h *Handler) ExportUsers(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	format := q.Get("format")
+	if format == "" {
+		format = "csv"
+	}
+	if format != "csv" && format != "ndjson" {
+		writeError(w, r, badParam("format", "must be 'csv' or 'ndjson'"))
+		return
+	}
+
+	params, err := parseFilterParams(q, "format")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	flusher, _ := w.(http.Flusher)
+	csvw := csv.NewWriter(w)
+	enc := json.NewEncoder(w)
+	started := false
+	start := func() {
+		started = true
+		if format == "csv" {
+			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+			w.Header().Set("Content-Disposition", `attachment; filename="users.csv"`)
+			csvw.Write([]string{"id", "name", "email", "gender", "birthdate"})
+		} else {
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			w.Header().Set("Content-Disposition", `attachment; filename="users.ndjson"`)
+		}
+	}
+
+	err = h.repo.ExportUsers(r.Context(), params, func(batch []models.User) error {
+		if !started {
+			start()
+		}
+
+		for _, u := range batch {
+			if format == "csv" {
+				csvw.Write([]string{
+					strconv.Itoa(u.ID), csvSafe(u.Name), csvSafe(u.Email), csvSafe(u.Gender), u.Birthdate.Format("2006-01-02"),
+				})
+			} else if err := enc.Encode(u); err != nil {
+				return err
+			}
+		}
+		if format == "csv" {
+			csvw.Flush()
+			if err := csvw.Error(); err != nil {
+				return err
+			}
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	})
+
+	switch {
+	case err != nil && !started:
+		writeError(w, r, err)
+	case err != nil && isClientGone(r, err):
+		log.Println("ℹ️  export cancelled by client")
+	case err != nil:
+		// The status line is already sent; all we can do is cut the stream short.
+		log.Println("❌ export aborted:", err)
+	case !started:
+		start()
+		csvw.Flush()
+	}
+}
+
+// csvSafe keeps spreadsheets from running a cell as a formula: a value that
+// starts with =, +, -, @, tab or CR gets a leading apostrophe, which Excel
+// and LibreOffice treat as "text". parseImportCSV strips it again, so a value
+// that already looks escaped gets a second apostrophe.
+func csvSafe(v string) string {
+	if v != "" && strings.ContainsRune(formulaPrefixes, rune(v[0])) || unescapeCSV(v) != v {
+		return "'" + v
+	}
+	return v
+}
+
+const formulaPrefixes = "=+-@\t\r"
@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"practice5/models"
+	"practice5/repository"
+)
+
+func TestExportCSVEscapesFormulas(t *testing.T) {
+	store := repository.NewMemoryStore()
+	name, email, gender, birthdate := "=HYPERLINK(\"http://x\")", "eve@mail.com", "female", "1990-01-01"
+	if _, err := store.CreateUser(context.Background(), models.UserInput{Name: &name, Email: &email, Gender: &gender, Birthdate: &birthdate}); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	New(store).ExportUsers(w, httptest.NewRequest("GET", "/users/export?format=csv", nil))
+	if !strings.Contains(w.Body.String(), `"'=HYPERLINK(""http://x"")"`) {
+		t.Fatalf("export body = %q; want the name prefixed with an apostrophe", w.Body.String())
+	}
+
+	rows, failures, err := parseImportCSV(strings.NewReader(w.Body.String()))
+	if err != nil || len(failures) > 0 || len(rows) != 1 {
+		t.Fatalf("re-import: rows=%+v failures=%+v err=%v", rows, failures, err)
+	}
+	if got := *rows[0].Input.Name; got != name {
+		t.Errorf("re-imported name = %q; want %q", got, name)
+	}
+}
+
+func TestCSVSafe(t *testing.T) {
+	for in, want := range map[string]string{
+		"Ann":     "Ann",
+		"":        "",
+		"=1+1":    "'=1+1",
+		"+1":      "'+1",
+		"-1":      "'-1",
+		"@SUM(1)": "'@SUM(1)",
+		"\tx":     "'\tx",
+		"'=x":     "''=x",
+		"'x":      "'x",
+	} {
+		if got := csvSafe(in); got != want {
+			t.Errorf("csvSafe(%q) = %q; want %q", in, got, want)
+		}
+	}
+}
+
+func TestExportNDJSONImportsBack(t *testing.T) {
+	store := repository.NewMemoryStore()
+	name, email, gender, birthdate := "Uma Young", "uma@mail.com", "female", "1998-02-14"
+	if _, err := store.CreateUser(context.Background(), models.UserInput{Name: &name, Email: &email, Gender: &gender, Birthdate: &birthdate}); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	New(store).ExportUsers(w, httptest.NewRequest("GET", "/users/export?format=ndjson", nil))
+	if !strings.Contains(w.Body.String(), `"id":`) {
+		t.Fatalf("export body = %q; want it to carry id", w.Body.String())
+	}
+
+	rows, failures, err := parseImportNDJSON(strings.NewReader(w.Body.String()))
+	if err != nil || len(failures) > 0 || len(rows) != 1 {
+		t.Fatalf("re-import: rows=%+v failures=%+v err=%v", rows, failures, err)
+	}
+	in := rows[0].Input
+	if *in.Name != name || *in.Email != email || *in.Gender != gender || *in.Birthdate != birthdate {
+		t.Errorf("re-imported input = %s %s %s %s", *in.Name, *in.Email, *in.Gender, *in.Birthdate)
+	}
+}
@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"practice5/models"
+)
+
+var userFields = []string{"id", "name", "email", "gender", "birthdate"}
+
+const (
+	defaultFriendsLimit = 5
+	maxFriendsLimit     = 50
+)
+
+// parseFieldsAndIncludes reads ?fields=id,name, ?include=friend_count,friends
+// and ?friends_limit=N (how many friends include=friends embeds).
+func parseFieldsAndIncludes(q url.Values, p *models.FilterParams) error {
+	if v := q.Get("fields"); v != "" {
+		for _, f := range strings.Split(v, ",") {
+			f = strings.TrimSpace(f)
+			if !contains(userFields, f) {
+				return badParam("fields", "unknown field %q, allowed: %s", f, strings.Join(userFields, ", "))
+			}
+			p.Fields = append(p.Fields, f)
+		}
+	}
+
+	limit, err := rangeParam(q, "friends_limit", defaultFriendsLimit, 1, maxFriendsLimit)
+	if err != nil {
+		return err
+	}
+	if v := q.Get("include"); v != "" {
+		for _, inc := range strings.Split(v, ",") {
+			switch strings.TrimSpace(inc) {
+			case "friend_count":
+				p.Include.FriendCount = true
+			case "friends":
+				p.Include.Friends = limit
+			default:
+				return badParam("include", "unknown relation %q, allowed: friend_count, friends", inc)
+			}
+		}
+	}
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// userView is a models.User cut down to a sparse fieldset; fields left nil
+// are not requested and stay out of the JSON.
+type userView struct {
+	ID          *int        `json:"id,omitempty"`
+	Name        *string     `json:"name,omitempty"`
+	Email       *string     `json:"email,omitempty"`
+	Gender      *string     `json:"gender,omitempty"`
+	Birthdate   *time.Time  `json:"birthdate,omitempty"`
+	FriendCount *int        `json:"friend_count,omitempty"`
+	Friends     *[]userView `json:"friends,omitempty"`
+}
+
+// pageView replaces the data of a page with views; the embedded response
+// keeps the pagination fields.
+type pageView struct {
+	Data []userView `json:"data"`
+	models.PaginatedResponse
+}
+
+// shapePage applies p.Fields and p.Include to a page. Without either the
+// page is returned untouched, so the default response does not change.
+func shapePage(page models.PaginatedResponse, p models.FilterParams) interface{} {
+	if p.Fields == nil && !p.Include.FriendCount && p.Include.Friends == 0 {
+		return page
+	}
+	fields := p.Fields
+	if fields == nil {
+		fields = userFields
+	}
+	return pageView{Data: viewUsers(page.Data, fields, p.Include), PaginatedResponse: page}
+}
+
+func viewUsers(users []models.User, fields []string, inc models.Include) []userView {
+	views := make([]userView, len(users))
+	for i := range users {
+		views[i] = viewUser(&users[i], fields, inc)
+	}
+	return views
+}
+
+// viewUser points into u; embedded friends get the same fieldset but no
+// relations of their own.
+func viewUser(u *models.User, fields []string, inc models.Include) userView {
+	var v userView
+	if inc.FriendCount {
+		v.FriendCount = u.FriendCount
+	}
+	for _, f := range fields {
+		switch f {
+		case "id":
+			v.ID = &u.ID
+		case "name":
+			v.Name = &u.Name
+		case "email":
+			v.Email = &u.Email
+		case "gender":
+			v.Gender = &u.Gender
+		case "birthdate":
+			v.Birthdate = &u.Birthdate
+		}
+	}
+	if inc.Friends > 0 {
+		friends := viewUsers(u.Friends, fields, models.Include{})
+		v.Friends = &friends
+	}
+	return v
+}
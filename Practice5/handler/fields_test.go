@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/url"
+	"testing"
+	"time"
+
+	"practice5/models"
+)
+
+func TestShapePage(t *testing.T) {
+	one, two := 1, 2
+	page := models.PaginatedResponse{
+		Data: []models.User{{
+			ID: 1, Name: "Alice", Email: "alice@mail.com", Gender: "female",
+			Birthdate:   time.Date(1995, 3, 12, 0, 0, 0, 0, time.UTC),
+			FriendCount: &two,
+			Friends:     []models.User{{ID: 2, Name: "Bob", Email: "bob@mail.com"}},
+		}, {
+			ID: 9, Name: "Ivan", FriendCount: new(int), Friends: []models.User{},
+		}},
+		TotalCount: &two, Page: &one, PageSize: 10,
+	}
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"fields=name,id",
+			`{"data":[{"id":1,"name":"Alice"},{"id":9,"name":"Ivan"}],"total_count":2,"page":1,"page_size":10}`},
+		{"fields=name&include=friend_count,friends",
+			`{"data":[{"name":"Alice","friend_count":2,"friends":[{"name":"Bob"}]},{"name":"Ivan","friend_count":0,"friends":[]}],"total_count":2,"page":1,"page_size":10}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, _ := url.ParseQuery(tt.query)
+			p, err := parseFilterParams(q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, _ := json.Marshal(shapePage(page, p))
+			if string(got) != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFieldsAndIncludesRejects(t *testing.T) {
+	for _, raw := range []string{"fields=id,salary", "include=posts", "include=friends&friends_limit=500"} {
+		q, _ := url.ParseQuery(raw)
+		if _, err := parseFilterParams(q); err == nil {
+			t.Errorf("parseFilterParams(%q) accepted invalid input", raw)
+		}
+	}
+}
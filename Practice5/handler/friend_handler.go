@@ -0,0 +1,194 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// GET /users/{id}/friends
+// Accepts the same query params as GET /users.
+func (h *Handler) GetFriends(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	params, err := parseFilterParams(r.URL.Query())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	result, err := h.repo.GetFriends(r.Context(), id, params)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, shapePage(result, params))
+}
+
+// GET /users/{id}/friend-requests?direction=incoming|outgoing
+// Lists pending requests; incoming is the default.
+func (h *Handler) GetFriendRequests(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	var outgoing bool
+	switch r.URL.Query().Get("direction") {
+	case "", "incoming":
+	case "outgoing":
+		outgoing = true
+	default:
+		writeError(w, r, badParam("direction", "must be 'incoming' or 'outgoing'"))
+		return
+	}
+
+	requests, err := h.repo.GetFriendRequests(r.Context(), id, outgoing)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, requests)
+}
+
+// POST /users/{id}/friend-requests/{other} — {id} asks {other} to be friends.
+func (h *Handler) SendFriendRequest(w http.ResponseWriter, r *http.Request) {
+	id, other, ok := pathPair(w, r)
+	if !ok {
+		return
+	}
+
+	fr, err := h.repo.SendFriendRequest(r.Context(), id, other)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, fr)
+}
+
+// POST /users/{id}/friend-requests/{other}/accept — {id} accepts the request from {other}.
+func (h *Handler) AcceptFriendRequest(w http.ResponseWriter, r *http.Request) {
+	h.pairAction(w, r, func(id, other int) error {
+		return h.repo.AcceptFriendRequest(r.Context(), other, id)
+	})
+}
+
+// POST /users/{id}/friend-requests/{other}/decline — {id} declines the request from {other}.
+func (h *Handler) DeclineFriendRequest(w http.ResponseWriter, r *http.Request) {
+	h.pairAction(w, r, func(id, other int) error {
+		return h.repo.DeclineFriendRequest(r.Context(), other, id)
+	})
+}
+
+// DELETE /users/{id}/friend-requests/{other} — {id} withdraws the request sent to {other}.
+func (h *Handler) CancelFriendRequest(w http.ResponseWriter, r *http.Request) {
+	h.pairAction(w, r, func(id, other int) error {
+		return h.repo.CancelFriendRequest(r.Context(), id, other)
+	})
+}
+
+// DELETE /users/{id}/friends/{other}
+func (h *Handler) Unfriend(w http.ResponseWriter, r *http.Request) {
+	h.pairAction(w, r, func(id, other int) error {
+		return h.repo.Unfriend(r.Context(), id, other)
+	})
+}
+
+func (h *Handler) pairAction(w http.ResponseWriter, r *http.Request, fn func(id, other int) error) {
+	id, other, ok := pathPair(w, r)
+	if !ok {
+		return
+	}
+	if err := fn(id, other); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// pathPair reads {id} and {other} and rejects a user befriending themselves.
+func pathPair(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return 0, 0, false
+	}
+	other, err := strconv.Atoi(r.PathValue("other"))
+	if err != nil || other < 1 {
+		writeError(w, r, badParam("other", "must be a positive integer"))
+		return 0, 0, false
+	}
+	if id == other {
+		writeError(w, r, badParam("other", "must differ from id"))
+		return 0, 0, false
+	}
+	return id, other, true
+}
+
+// GET /users/{id}/suggestions?limit=10&sample=3
+// Friends of friends ranked by mutual friend count, with up to sample mutual friends' names.
+func (h *Handler) GetSuggestions(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	q := r.URL.Query()
+
+	limit, err := rangeParam(q, "limit", 10, 1, 50)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	sample, err := rangeParam(q, "sample", 3, 1, 10)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	suggestions, err := h.repo.GetFriendSuggestions(r.Context(), id, limit, sample)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, suggestions)
+}
+
+const (
+	defaultPathDepth = 6
+	maxPathDepth     = 10
+)
+
+// GET /users/path?from=1&to=9&max_depth=6
+// Shortest chain of friends between two users; 404 when none within max_depth.
+func (h *Handler) GetFriendPath(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	from, err := intParam(q, "from")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	to, err := intParam(q, "to")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	depth := defaultPathDepth
+	if v := q.Get("max_depth"); v != "" {
+		d, err := strconv.Atoi(v)
+		if err != nil || d < 1 || d > maxPathDepth {
+			writeError(w, r, badParam("max_depth", "must be an integer between 1 and %d", maxPathDepth))
+			return
+		}
+		depth = d
+	}
+
+	path, err := h.repo.FindFriendPath(r.Context(), from, to, depth)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, path)
+}
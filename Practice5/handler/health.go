@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// readyTimeout bounds a whole /readyz run; a dependency that cannot answer
+// within it counts as down.
+const readyTimeout = 2 * time.Second
+
+type readyCheck struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+// Health serves the liveness and readiness probes.
+type Health struct {
+	checks   []readyCheck
+	draining atomic.Bool
+}
+
+func NewHealth() *Health {
+	return &Health{}
+}
+
+// AddCheck registers a dependency /readyz must find healthy.
+func (hc *Health) AddCheck(name string, check func(ctx context.Context) error) {
+	hc.checks = append(hc.checks, readyCheck{name, check})
+}
+
+// Drain makes /readyz fail from now on, so the load balancer stops sending
+// new requests while in-flight ones finish.
+func (hc *Health) Drain() {
+	hc.draining.Store(true)
+}
+
+// GET /healthz
+// The process is up and serving HTTP. Dependencies are not checked, so a
+// database outage does not get the instance restarted.
+func (hc *Health) Liveness(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// GET /readyz
+// 200 when every registered check passes, otherwise 503 naming the failures.
+func (hc *Health) Readiness(w http.ResponseWriter, r *http.Request) {
+	if hc.draining.Load() {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"status": "draining"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), readyTimeout)
+	defer cancel()
+
+	status, code := "ready", http.StatusOK
+	checks := map[string]string{}
+	for _, c := range hc.checks {
+		if err := c.check(ctx); err != nil {
+			status, code = "unavailable", http.StatusServiceUnavailable
+			checks[c.name] = err.Error()
+			continue
+		}
+		checks[c.name] = "ok"
+	}
+	writeJSON(w, code, map[string]interface{}{"status": status, "checks": checks})
+}
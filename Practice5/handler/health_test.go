@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestReadiness(t *testing.T) {
+	ok := func(context.Context) error { return nil }
+	down := func(context.Context) error { return errors.New("connection refused") }
+
+	tests := []struct {
+		name   string
+		checks []func(context.Context) error
+		drain  bool
+		want   int
+	}{
+		{"no checks", nil, false, http.StatusOK},
+		{"all pass", []func(context.Context) error{ok, ok}, false, http.StatusOK},
+		{"one fails", []func(context.Context) error{ok, down}, false, http.StatusServiceUnavailable},
+		{"draining", []func(context.Context) error{ok}, true, http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hc := NewHealth()
+			for i, c := range tt.checks {
+				hc.AddCheck(string(rune('a'+i)), c)
+			}
+			if tt.drain {
+				hc.Drain()
+			}
+			w := httptest.NewRecorder()
+			hc.Readiness(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			if w.Code != tt.want {
+				t.Errorf("status = %d; want %d (%s)", w.Code, tt.want, w.Body)
+			}
+		})
+	}
+}
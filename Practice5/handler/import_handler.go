@@ -0,0 +1,214 @@
+package handler
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"practice5/models"
+)
+
+const maxImportBytes = 10 << 20
+
+// POST /users/import?mode=all-or-nothing|best-effort&format=csv|ndjson
+// format defaults from Content-Type (text/csv or application/x-ndjson).
+// CSV needs a header row naming name, email, gender and birthdate (id is ignored).
+// all-or-nothing (the default) answers 422 with the error report and imports
+// nothing if any line fails; best-effort imports every valid line.
+func (h *Handler) ImportUsers(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	mode := q.Get("mode")
+	if mode == "" {
+		mode = "all-or-nothing"
+	}
+	if mode != "all-or-nothing" && mode != "best-effort" {
+		writeError(w, r, badParam("mode", "must be 'all-or-nothing' or 'best-effort'"))
+		return
+	}
+
+	format := q.Get("format")
+	if format == "" {
+		format = "csv"
+		if strings.Contains(r.Header.Get("Content-Type"), "ndjson") {
+			format = "ndjson"
+		}
+	}
+
+	body := http.MaxBytesReader(w, r.Body, maxImportBytes)
+	var rows []models.ImportRow
+	var failures []models.ImportError
+	var err error
+	switch format {
+	case "csv":
+		rows, failures, err = parseImportCSV(body)
+	case "ndjson":
+		rows, failures, err = parseImportNDJSON(body)
+	default:
+		writeError(w, r, badParam("format", "must be 'csv' or 'ndjson'"))
+		return
+	}
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeProblem(w, r, http.StatusRequestEntityTooLarge, "body_too_large", fmt.Sprintf("body exceeds %d bytes", maxImportBytes))
+		return
+	}
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "invalid_body", err.Error())
+		return
+	}
+
+	result := models.ImportResult{Mode: mode, Total: len(rows) + len(failures)}
+	allOrNothing := mode == "all-or-nothing"
+	if !allOrNothing || len(failures) == 0 {
+		imported, dbFailures, err := h.repo.ImportUsers(r.Context(), rows, allOrNothing)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		result.Imported = imported
+		failures = append(failures, dbFailures...)
+	}
+	sortImportErrors(failures)
+	result.Errors = failures
+	if result.Errors == nil {
+		result.Errors = []models.ImportError{}
+	}
+	result.Failed = len(failures)
+
+	status := http.StatusOK
+	if allOrNothing && len(failures) > 0 {
+		status = http.StatusUnprocessableEntity
+	}
+	writeJSON(w, status, result)
+}
+
+// parseImportCSV validates every record; invalid ones become failures.
+// The returned error is reserved for input that cannot be read at all.
+func parseImportCSV(body io.Reader) ([]models.ImportRow, []models.ImportError, error) {
+	cr := csv.NewReader(body)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, nil, errors.New("empty CSV: a header row is required")
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	cols := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch name {
+		case "id":
+		case "name", "email", "gender", "birthdate":
+			cols[name] = i
+		default:
+			return nil, nil, fmt.Errorf("unknown CSV column %q", name)
+		}
+	}
+	for _, name := range []string{"name", "email", "gender", "birthdate"} {
+		if _, ok := cols[name]; !ok {
+			return nil, nil, fmt.Errorf("CSV header is missing the %q column", name)
+		}
+	}
+
+	var rows []models.ImportRow
+	var failures []models.ImportError
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return rows, failures, nil
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			failures = append(failures, models.ImportError{Line: parseErr.Line, Message: parseErr.Err.Error()})
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		line, _ := cr.FieldPos(0)
+
+		field := func(name string) *string {
+			if i := cols[name]; i < len(record) {
+				v := unescapeCSV(record[i])
+				return &v
+			}
+			return nil
+		}
+		in := models.UserInput{
+			Name: field("name"), Email: field("email"), Gender: field("gender"), Birthdate: field("birthdate"),
+		}
+		rows, failures = appendImportRow(rows, failures, line, in)
+	}
+}
+
+// unescapeCSV undoes csvSafe so that an export imports back unchanged.
+func unescapeCSV(v string) string {
+	if len(v) > 1 && v[0] == '\'' && strings.ContainsRune(formulaPrefixes, rune(v[1])) {
+		return v[1:]
+	}
+	return v
+}
+
+func parseImportNDJSON(body io.Reader) ([]models.ImportRow, []models.ImportError, error) {
+	sc := bufio.NewScanner(body)
+	sc.Buffer(make([]byte, 64*1024), 1<<20)
+
+	var rows []models.ImportRow
+	var failures []models.ImportError
+	for line := 1; sc.Scan(); line++ {
+		b := bytes.TrimSpace(sc.Bytes())
+		if len(b) == 0 {
+			continue
+		}
+		// id is what our own export writes; like the CSV column it is ignored.
+		var in struct {
+			models.UserInput
+			ID json.RawMessage `json:"id"`
+		}
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&in); err != nil {
+			failures = append(failures, models.ImportError{Line: line, Message: "invalid JSON: " + err.Error()})
+			continue
+		}
+		// Exported users carry birthdate as a JSON time ("1998-02-14T00:00:00Z").
+		if bd := in.Birthdate; bd != nil {
+			if t, err := time.Parse(time.RFC3339, *bd); err == nil && t.Equal(t.Truncate(24*time.Hour)) {
+				date := t.Format("2006-01-02")
+				in.Birthdate = &date
+			}
+		}
+		rows, failures = appendImportRow(rows, failures, line, in.UserInput)
+	}
+	return rows, failures, sc.Err()
+}
+
+func appendImportRow(rows []models.ImportRow, failures []models.ImportError, line int, in models.UserInput) ([]models.ImportRow, []models.ImportError) {
+	if errs := validateUserInput(&in, false); len(errs) > 0 {
+		msgs := make([]string, len(errs))
+		for i, e := range errs {
+			msgs[i] = e.Field + ": " + e.Message
+		}
+		var email string
+		if in.Email != nil {
+			email = *in.Email
+		}
+		return rows, append(failures, models.ImportError{Line: line, Email: email, Message: strings.Join(msgs, "; ")})
+	}
+	return append(rows, models.ImportRow{Line: line, Input: in}), failures
+}
+
+func sortImportErrors(errs []models.ImportError) {
+	sort.SliceStable(errs, func(i, j int) bool { return errs[i].Line < errs[j].Line })
+}
@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"strings"
+	"testing"
+
+	"practice5/models"
+)
+
+func TestParseImportCSV(t *testing.T) {
+	body := `id,name,email,gender,birthdate
+99,Uma Young,Uma@mail.com,female,1998-02-14
+,Vic Stone,vic@mail.com,robot,1990-01-01
+,Walt Ng,walt@mail.com,male,1990-13-01
+,Xena Ray,xena@mail.com,female,2000-01-01
+`
+	rows, failures, err := parseImportCSV(strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("parseImportCSV: %v", err)
+	}
+	if len(rows) != 2 || rows[0].Line != 2 || rows[1].Line != 5 || *rows[0].Input.Email != "uma@mail.com" {
+		t.Errorf("rows = %+v", rows)
+	}
+	wantLines := []int{3, 4}
+	if len(failures) != len(wantLines) {
+		t.Fatalf("failures = %+v; want lines %v", failures, wantLines)
+	}
+	for i, f := range failures {
+		if f.Line != wantLines[i] {
+			t.Errorf("failure %d on line %d; want %d", i, f.Line, wantLines[i])
+		}
+	}
+	if !strings.Contains(failures[0].Message, "gender") || !strings.Contains(failures[1].Message, "birthdate") {
+		t.Errorf("failure messages = %q, %q", failures[0].Message, failures[1].Message)
+	}
+}
+
+func TestParseImportCSVHeader(t *testing.T) {
+	for body, want := range map[string]string{
+		"":                                "header row is required",
+		"name,email,gender\n":             `missing the "birthdate" column`,
+		"name,email,gender,birthdate,x\n": `unknown CSV column "x"`,
+	} {
+		_, _, err := parseImportCSV(strings.NewReader(body))
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("parseImportCSV(%q) error = %v; want %q", body, err, want)
+		}
+	}
+}
+
+func TestParseImportNDJSON(t *testing.T) {
+	body := `{"name":"Uma Young","email":"uma@mail.com","gender":"female","birthdate":"1998-02-14"}
+
+{"name":"Vic Stone","email":"vic@mail.com"
+{"name":"Walt Ng","email":"walt@mail.com","gender":"male","birthdate":"1990-01-01"}
+{"name":"Xena Ray","email":"xena@mail.com","gender":"female","birthdate":"2000-01-01","salary":1}
+`
+	rows, failures, err := parseImportNDJSON(strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("parseImportNDJSON: %v", err)
+	}
+	if len(rows) != 2 || rows[0].Line != 1 || rows[1].Line != 4 {
+		t.Errorf("rows = %+v", rows)
+	}
+	if len(failures) != 2 || failures[0].Line != 3 || failures[1].Line != 5 {
+		t.Fatalf("failures = %+v; want lines 3 and 5", failures)
+	}
+	if !strings.Contains(failures[1].Message, `unknown field "salary"`) {
+		t.Errorf("failure message = %q; want it to name the unknown field", failures[1].Message)
+	}
+}
+
+func TestSortImportErrors(t *testing.T) {
+	errs := []models.ImportError{{Line: 7}, {Line: 2}, {Line: 5}}
+	sortImportErrors(errs)
+	if errs[0].Line != 2 || errs[1].Line != 5 || errs[2].Line != 7 {
+		t.Errorf("not sorted: %+v", errs)
+	}
+}
@@ -0,0 +1,283 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	swaggerFiles "github.com/swaggo/files/v2"
+
+	"practice5/models"
+)
+
+// The OpenAPI 3 document covers the list endpoints. Response schemas are
+// derived from the Go types the handlers encode, so they follow the models;
+// parameters are listed here, and TestOpenAPIMatchesHandlers checks them
+// against what the handlers actually read.
+
+type openAPIParam struct {
+	Name        string                 `json:"name"`
+	In          string                 `json:"in"`
+	Description string                 `json:"description,omitempty"`
+	Required    bool                   `json:"required,omitempty"`
+	Style       string                 `json:"style,omitempty"`
+	Explode     *bool                  `json:"explode,omitempty"`
+	Schema      map[string]interface{} `json:"schema"`
+}
+
+// schema builds {"type": typ} plus the key/value pairs in kv.
+func schema(typ string, kv ...interface{}) map[string]interface{} {
+	s := map[string]interface{}{"type": typ}
+	for i := 0; i+1 < len(kv); i += 2 {
+		s[kv[i].(string)] = kv[i+1]
+	}
+	return s
+}
+
+func queryParam(name, description string, s map[string]interface{}) openAPIParam {
+	return openAPIParam{Name: name, In: "query", Description: description, Schema: s}
+}
+
+// listParam is a comma-separated list such as ?id=1,2,3.
+func listParam(name, description string, items map[string]interface{}) openAPIParam {
+	explode := false
+	p := queryParam(name, description, schema("array", "items", items))
+	p.Style, p.Explode = "form", &explode
+	return p
+}
+
+// filterParams documents every query param parseFilterParams reads; it
+// also decides which params it accepts.
+var filterParams = []openAPIParam{
+	queryParam("page", "Page number in offset mode.", schema("integer", "minimum", 1, "maximum", maxPage, "default", 1)),
+	queryParam("page_size", "Users per page.", schema("integer", "minimum", 1, "maximum", maxPageSize, "default", 10)),
+	queryParam("sort", "Comma-separated columns, `-` for descending, e.g. `gender,-birthdate,name`. "+
+		"Takes precedence over order_by/order_dir; id is always the final tiebreaker.", schema("string")),
+	queryParam("order_by", "Single sort column.", schema("string", "enum", userFields)),
+	queryParam("order_dir", "Direction for order_by, case-insensitive.", schema("string", "enum", []string{"ASC", "DESC", "asc", "desc"})),
+	listParam("id", "Only these ids.", schema("integer")),
+	queryParam("name", "Case-insensitive substring of the name.", schema("string")),
+	queryParam("email", "Case-insensitive substring of the email.", schema("string")),
+	listParam("gender", "Any of these genders.", schema("string")),
+	queryParam("birthdate", "Exact birthdate.", schema("string", "format", "date")),
+	queryParam("birthdate_from", "Born on or after.", schema("string", "format", "date")),
+	queryParam("birthdate_to", "Born on or before.", schema("string", "format", "date")),
+	queryParam("age_min", "At least this old.", schema("integer", "minimum", 0)),
+	queryParam("age_max", "At most this old.", schema("integer", "minimum", 0)),
+	queryParam("filter", "Expression ANDed with the other filters, e.g. `gender = female AND (age >= 25 OR name ~ 'ali')`.", schema("string")),
+	queryParam("cursor", "Switches to keyset pagination: empty for the first page, then next_cursor or prev_cursor. "+
+		"total_count and page are left out in this mode.", schema("string")),
+	listParam("fields", "Return only these columns.", schema("string", "enum", userFields)),
+	listParam("include", "Relations to embed in each user.", schema("string", "enum", []string{"friend_count", "friends"})),
+	queryParam("friends_limit", "How many friends include=friends embeds, lowest id first.",
+		schema("integer", "minimum", 1, "maximum", maxFriendsLimit, "default", defaultFriendsLimit)),
+}
+
+// usersParams documents every query param GetUsers reads.
+var usersParams = append(filterParams[:len(filterParams):len(filterParams)], includeDeletedParam)
+
+var includeDeletedParam = queryParam("include_deleted", "Also return soft-deleted users, with deleted_at set. Admins only.",
+	schema("boolean", "default", false))
+
+var commonFriendsParams = []openAPIParam{
+	{Name: "user1", In: "query", Required: true, Schema: schema("integer")},
+	{Name: "user2", In: "query", Required: true, Description: "Must differ from user1.", Schema: schema("integer")},
+	includeDeletedParam,
+}
+
+// schemaNames names the component schemas of unexported or generic types.
+var schemaNames = map[reflect.Type]string{
+	reflect.TypeOf(problem{}):    "Problem",
+	reflect.TypeOf(fieldError{}): "FieldError",
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// openAPISchemas collects the component schemas derived from Go types,
+// following their json tags; fields without omitempty are required.
+type openAPISchemas map[string]interface{}
+
+func (s openAPISchemas) of(t reflect.Type) map[string]interface{} {
+	switch {
+	case t == timeType:
+		return schema("string", "format", "date-time")
+	case t.Kind() == reflect.Pointer:
+		return s.of(t.Elem())
+	case t.Kind() == reflect.Slice:
+		return schema("array", "items", s.of(t.Elem()))
+	case t.Kind() == reflect.Struct:
+		return s.ref(t)
+	case t.Kind() == reflect.String:
+		return schema("string")
+	case t.Kind() == reflect.Bool:
+		return schema("boolean")
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		return schema("integer")
+	}
+	return schema("number")
+}
+
+// ref registers t as a component schema and returns a reference to it.
+func (s openAPISchemas) ref(t reflect.Type) map[string]interface{} {
+	name, ok := schemaNames[t]
+	if !ok {
+		name = t.Name()
+	}
+	if _, done := s[name]; !done {
+		s[name] = nil // User refers to itself through Friends
+		s[name] = s.object(t)
+	}
+	return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+}
+
+func (s openAPISchemas) object(t reflect.Type) map[string]interface{} {
+	props := map[string]interface{}{}
+	var required []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" || !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		props[name] = s.of(f.Type)
+		if !strings.Contains(opts, "omitempty") {
+			required = append(required, name)
+		}
+	}
+	obj := schema("object", "properties", props)
+	if required != nil {
+		obj["required"] = required
+	}
+	return obj
+}
+
+func openAPIDocument() map[string]interface{} {
+	schemas := openAPISchemas{}
+	content := func(mediaType string, s map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{mediaType: map[string]interface{}{"schema": s}}
+	}
+	response := func(description string, s map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{
+			"description": description,
+			"headers": map[string]interface{}{
+				"ETag": map[string]interface{}{"description": "Weak tag; send it back in If-None-Match.", "schema": schema("string")},
+			},
+			"content": content("application/json", s),
+		}
+	}
+	problemRef := schemas.of(reflect.TypeOf(problem{}))
+	problemResponse := func(description string) map[string]interface{} {
+		return map[string]interface{}{"description": description, "content": content("application/problem+json", problemRef)}
+	}
+	errors := map[string]interface{}{
+		"304": map[string]interface{}{"description": "The If-None-Match tag still matches."},
+		"400": problemResponse("Invalid parameter; `errors` names it."),
+		"401": problemResponse("Missing or invalid bearer token (only with auth configured)."),
+		"403": problemResponse("include_deleted without role admin."),
+		"503": problemResponse("The query timed out."),
+	}
+	withErrors := func(ok map[string]interface{}) map[string]interface{} {
+		responses := map[string]interface{}{"200": ok}
+		for code, r := range errors {
+			responses[code] = r
+		}
+		return responses
+	}
+	etag := openAPIParam{Name: "If-None-Match", In: "header", Schema: schema("string")}
+	security := []map[string][]string{{"bearerAuth": {}}}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   "Practice5 users API",
+			"version": "1.0.0",
+		},
+		"paths": map[string]interface{}{
+			"/users": map[string]interface{}{
+				"get": map[string]interface{}{
+					"operationId": "listUsers",
+					"summary":     "List users with filters, sorting and pagination",
+					"description": "fields and include change which properties each user carries; the rest of the page is unchanged.",
+					"parameters":  append(append([]openAPIParam(nil), usersParams...), etag),
+					"responses":   withErrors(response("A page of users.", schemas.of(reflect.TypeOf(models.PaginatedResponse{})))),
+					"security":    security,
+				},
+			},
+			"/users/common-friends": map[string]interface{}{
+				"get": map[string]interface{}{
+					"operationId": "commonFriends",
+					"summary":     "Users who are friends of both user1 and user2",
+					"parameters":  append(append([]openAPIParam(nil), commonFriendsParams...), etag),
+					"responses":   withErrors(response("The common friends, in no particular order.", schemas.of(reflect.TypeOf([]models.User{})))),
+					"security":    security,
+				},
+			},
+		},
+		"components": map[string]interface{}{
+			"schemas": schemas,
+			"securitySchemes": map[string]interface{}{
+				"bearerAuth": map[string]interface{}{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+			},
+		},
+	}
+}
+
+var openAPIJSON = sync.OnceValue(func() []byte {
+	data, err := json.MarshalIndent(openAPIDocument(), "", "  ")
+	if err != nil {
+		panic(err)
+	}
+	return data
+})
+
+// GET /openapi.json
+func OpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openAPIJSON())
+}
+
+// swaggerUI points Swagger UI at /openapi.json. Its assets are served from
+// the binary, so /docs works without internet access.
+const swaggerUI = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>Practice5 API</title>
+  <link rel="stylesheet" href="/docs/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="/docs/swagger-ui-bundle.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({ url: "/openapi.json", dom_id: "#swagger-ui" });
+  </script>
+</body>
+</html>
+`
+
+// GET /docs
+func SwaggerUI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(swaggerUI))
+}
+
+// swaggerUIAssets are the files of swaggerFiles.FS (Swagger UI from
+// swagger-ui-dist, Apache-2.0) that the page above loads.
+var swaggerUIAssets = map[string]bool{"swagger-ui.css": true, "swagger-ui-bundle.js": true}
+
+// GET /docs/{file}
+func SwaggerUIAsset(w http.ResponseWriter, r *http.Request) {
+	file := r.PathValue("file")
+	if !swaggerUIAssets[file] {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	http.ServeFileFS(w, r, swaggerFiles.FS, file)
+}
@@ -0,0 +1,240 @@
+package handler
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// TestOpenAPIMatchesHandlers fails when a handler starts or stops reading a
+// query param without the spec following. It reads this package's source:
+// starting from the handler, it follows calls to functions of the package
+// and collects the names passed to q.Get, q.Has, r.URL.Query().Get,
+// intParam and rangeParam, plus the name column of the {name, dst} tables
+// parseFilterParams loops over.
+func TestOpenAPIMatchesHandlers(t *testing.T) {
+	funcs := packageFuncs(t)
+	doc := openAPIDocument()
+	paths := doc["paths"].(map[string]interface{})
+
+	for path, handler := range map[string]string{
+		"/users":                "GetUsers",
+		"/users/common-friends": "GetCommonFriends",
+	} {
+		var spec []string
+		op := paths[path].(map[string]interface{})["get"].(map[string]interface{})
+		for _, p := range op["parameters"].([]openAPIParam) {
+			if p.In == "query" {
+				spec = append(spec, p.Name)
+			}
+		}
+		sort.Strings(spec)
+
+		read := map[string]bool{}
+		collectParams(funcs, handler, map[string]bool{}, read)
+		var got []string
+		for name := range read {
+			got = append(got, name)
+		}
+		sort.Strings(got)
+
+		if !reflect.DeepEqual(got, spec) {
+			t.Errorf("%s: %s reads %v\nbut the spec lists %v", path, handler, got, spec)
+		}
+	}
+}
+
+func packageFuncs(t *testing.T) map[string]*ast.FuncDecl {
+	t.Helper()
+	files, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	funcs := map[string]*ast.FuncDecl{}
+	fset := token.NewFileSet()
+	for _, name := range files {
+		if strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		src, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f, err := parser.ParseFile(fset, name, src, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, d := range f.Decls {
+			if fn, ok := d.(*ast.FuncDecl); ok {
+				funcs[fn.Name.Name] = fn
+			}
+		}
+	}
+	return funcs
+}
+
+func collectParams(funcs map[string]*ast.FuncDecl, name string, seen, params map[string]bool) {
+	fn, ok := funcs[name]
+	if !ok || seen[name] {
+		return
+	}
+	seen[name] = true
+
+	literal := func(e ast.Expr) (string, bool) {
+		lit, ok := e.(*ast.BasicLit)
+		if !ok || lit.Kind != token.STRING {
+			return "", false
+		}
+		s, err := strconv.Unquote(lit.Value)
+		return s, err == nil
+	}
+
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.CallExpr:
+			switch fun := n.Fun.(type) {
+			case *ast.Ident:
+				if (fun.Name == "intParam" || fun.Name == "rangeParam") && len(n.Args) > 1 {
+					if s, ok := literal(n.Args[1]); ok {
+						params[s] = true
+					}
+				}
+				collectParams(funcs, fun.Name, seen, params)
+			case *ast.SelectorExpr:
+				if (fun.Sel.Name == "Get" || fun.Sel.Name == "Has") && isQueryValues(fun.X) && len(n.Args) > 0 {
+					if s, ok := literal(n.Args[0]); ok {
+						params[s] = true
+					}
+				}
+				collectParams(funcs, fun.Sel.Name, seen, params) // methods such as h.notModified
+			}
+		case *ast.CompositeLit:
+			// []struct{ name string; dst **T }{{"birthdate", &p.Birthdate}, ...}
+			arr, ok := n.Type.(*ast.ArrayType)
+			if !ok {
+				return true
+			}
+			st, ok := arr.Elt.(*ast.StructType)
+			if !ok || len(st.Fields.List) == 0 || len(st.Fields.List[0].Names) == 0 || st.Fields.List[0].Names[0].Name != "name" {
+				return true
+			}
+			for _, el := range n.Elts {
+				if row, ok := el.(*ast.CompositeLit); ok && len(row.Elts) > 0 {
+					if s, ok := literal(row.Elts[0]); ok {
+						params[s] = true
+					}
+				}
+			}
+		}
+		return true
+	})
+}
+
+// isQueryValues matches the url.Values receivers the handlers use: a
+// variable named q, or r.URL.Query() itself.
+func isQueryValues(x ast.Expr) bool {
+	if id, ok := x.(*ast.Ident); ok {
+		return id.Name == "q"
+	}
+	call, ok := x.(*ast.CallExpr)
+	if !ok {
+		return false
+	}
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	return ok && sel.Sel.Name == "Query"
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	w := httptest.NewRecorder()
+	OpenAPI(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d", w.Code)
+	}
+
+	var doc struct {
+		OpenAPI    string `json:"openapi"`
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]json.RawMessage `json:"properties"`
+				Required   []string                   `json:"required"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.OpenAPI != "3.0.3" {
+		t.Errorf("openapi = %q", doc.OpenAPI)
+	}
+
+	// Every property the models encode is described, and only the
+	// always-present ones are required.
+	tests := []struct {
+		schema   string
+		props    []string
+		required []string
+	}{
+		{"User", []string{"birthdate", "deleted_at", "email", "friend_count", "friends", "gender", "id", "name"},
+			[]string{"id", "name", "email", "gender", "birthdate"}},
+		{"PaginatedResponse", []string{"data", "next_cursor", "page", "page_size", "prev_cursor", "total_count"},
+			[]string{"data", "page_size"}},
+		{"Problem", []string{"code", "detail", "errors", "instance", "request_id", "status", "title", "type"},
+			[]string{"type", "title", "status", "code"}},
+	}
+	for _, tt := range tests {
+		s, ok := doc.Components.Schemas[tt.schema]
+		if !ok {
+			t.Errorf("schema %s missing", tt.schema)
+			continue
+		}
+		var props []string
+		for p := range s.Properties {
+			props = append(props, p)
+		}
+		sort.Strings(props)
+		if !reflect.DeepEqual(props, tt.props) {
+			t.Errorf("%s properties = %v; want %v", tt.schema, props, tt.props)
+		}
+		if !reflect.DeepEqual(s.Required, tt.required) {
+			t.Errorf("%s required = %v; want %v", tt.schema, s.Required, tt.required)
+		}
+	}
+}
+
+func TestSwaggerUIServesEmbeddedAssets(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /docs", SwaggerUI)
+	mux.HandleFunc("GET /docs/{file}", SwaggerUIAsset)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/docs", nil))
+	if page := w.Body.String(); strings.Contains(page, "https://") {
+		t.Errorf("/docs loads assets from another host:\n%s", page)
+	}
+
+	for path, want := range map[string]int{
+		"/docs/swagger-ui.css":       http.StatusOK,
+		"/docs/swagger-ui-bundle.js": http.StatusOK,
+		"/docs/index.html":           http.StatusNotFound,
+		"/docs/swagger-ui.css.map":   http.StatusNotFound,
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != want {
+			t.Errorf("GET %s = %d; want %d", path, w.Code, want)
+		}
+		if want == http.StatusOK && w.Body.Len() == 0 {
+			t.Errorf("GET %s returned an empty body", path)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"practice5/models"
+)
+
+var allowedGenders = map[string]bool{
+	"male":   true,
+	"female": true,
+}
+
+type fieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// POST /users
+func (h *Handler) CreateUser(w http.ResponseWriter, r *http.Request) {
+	in, ok := decodeUserInput(w, r, false)
+	if !ok {
+		return
+	}
+
+	u, err := h.repo.CreateUser(r.Context(), in)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/users/%d", u.ID))
+	writeJSON(w, http.StatusCreated, u)
+}
+
+// GET /users/{id}
+func (h *Handler) GetUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	u, err := h.repo.GetUserByID(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, u)
+}
+
+// PUT /users/{id} replaces every field; PATCH /users/{id} only the ones sent.
+func (h *Handler) UpdateUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	in, ok := decodeUserInput(w, r, r.Method == http.MethodPatch)
+	if !ok {
+		return
+	}
+
+	u, err := h.repo.UpdateUser(r.Context(), id, in)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, u)
+}
+
+// DELETE /users/{id}
+// Soft delete: see RestoreUser.
+func (h *Handler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.DeleteUser(r.Context(), id); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// POST /users/{id}/restore
+// Undoes DELETE /users/{id}, friendships included, until the purge job has
+// removed the user for good.
+func (h *Handler) RestoreUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	u, err := h.repo.RestoreUser(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, u)
+}
+
+func pathID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		writeError(w, r, badParam("id", "must be a positive integer"))
+		return 0, false
+	}
+	return id, true
+}
+
+func decodeUserInput(w http.ResponseWriter, r *http.Request, partial bool) (models.UserInput, bool) {
+	var in models.UserInput
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&in); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "invalid_body", "invalid JSON body: "+err.Error())
+		return in, false
+	}
+
+	if errs := validateUserInput(&in, partial); len(errs) > 0 {
+		writeError(w, r, validationErrors(errs))
+		return in, false
+	}
+	return in, true
+}
+
+// validateUserInput normalizes in place and reports every invalid field.
+// Unless partial is set, all fields are required.
+func validateUserInput(in *models.UserInput, partial bool) []fieldError {
+	var errs []fieldError
+	required := func(field string, v *string) bool {
+		if v == nil {
+			if !partial {
+				errs = append(errs, fieldError{field, "is required"})
+			}
+			return false
+		}
+		*v = strings.TrimSpace(*v)
+		return true
+	}
+
+	if required("name", in.Name) {
+		if *in.Name == "" || len(*in.Name) > 100 {
+			errs = append(errs, fieldError{"name", "must be 1-100 characters"})
+		}
+	}
+	if required("email", in.Email) {
+		*in.Email = strings.ToLower(*in.Email)
+		if a, err := mail.ParseAddress(*in.Email); err != nil || a.Address != *in.Email || len(*in.Email) > 100 {
+			errs = append(errs, fieldError{"email", "must be a valid address of at most 100 characters"})
+		}
+	}
+	if required("gender", in.Gender) {
+		*in.Gender = strings.ToLower(*in.Gender)
+		if !allowedGenders[*in.Gender] {
+			errs = append(errs, fieldError{"gender", "must be 'male' or 'female'"})
+		}
+	}
+	if required("birthdate", in.Birthdate) {
+		d, err := time.Parse("2006-01-02", *in.Birthdate)
+		switch {
+		case err != nil:
+			errs = append(errs, fieldError{"birthdate", "must be a date in YYYY-MM-DD format"})
+		case d.After(time.Now()):
+			errs = append(errs, fieldError{"birthdate", "must not be in the future"})
+		case d.Year() < 1900:
+			errs = append(errs, fieldError{"birthdate", "must not be before 1900"})
+		}
+	}
+	return errs
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
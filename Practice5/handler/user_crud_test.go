@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"reflect"
+	"testing"
+
+	"practice5/models"
+)
+
+func strPtr(s string) *string { return &s }
+
+func TestValidateUserInput(t *testing.T) {
+	valid := func() models.UserInput {
+		return models.UserInput{
+			Name:      strPtr(" Alice Johnson "),
+			Email:     strPtr("Alice@Mail.com"),
+			Gender:    strPtr("Female"),
+			Birthdate: strPtr("1995-03-12"),
+		}
+	}
+
+	tests := []struct {
+		name    string
+		mutate  func(*models.UserInput)
+		partial bool
+		want    []string // invalid fields
+	}{
+		{"valid", func(*models.UserInput) {}, false, nil},
+		{"missing fields on create", func(in *models.UserInput) { in.Email, in.Gender = nil, nil }, false, []string{"email", "gender"}},
+		{"missing fields on patch", func(in *models.UserInput) { in.Email, in.Gender = nil, nil }, true, nil},
+		{"bad gender", func(in *models.UserInput) { in.Gender = strPtr("robot") }, false, []string{"gender"}},
+		{"bad date", func(in *models.UserInput) { in.Birthdate = strPtr("12.03.1995") }, false, []string{"birthdate"}},
+		{"future date", func(in *models.UserInput) { in.Birthdate = strPtr("2999-01-01") }, true, []string{"birthdate"}},
+		{"bad email", func(in *models.UserInput) { in.Email = strPtr("alice") }, true, []string{"email"}},
+		{"blank name", func(in *models.UserInput) { in.Name = strPtr("  ") }, true, []string{"name"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := valid()
+			tt.mutate(&in)
+			var got []string
+			for _, e := range validateUserInput(&in, tt.partial) {
+				got = append(got, e.Field)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("invalid fields = %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateUserInputNormalizes(t *testing.T) {
+	in := models.UserInput{
+		Name:      strPtr(" Alice Johnson "),
+		Email:     strPtr("Alice@Mail.com"),
+		Gender:    strPtr("Female"),
+		Birthdate: strPtr("1995-03-12"),
+	}
+	if errs := validateUserInput(&in, false); len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	if *in.Name != "Alice Johnson" || *in.Email != "alice@mail.com" || *in.Gender != "female" {
+		t.Errorf("not normalized: %q %q %q", *in.Name, *in.Email, *in.Gender)
+	}
+}
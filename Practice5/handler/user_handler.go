@@ -3,45 +3,106 @@ package handler
 import (
 	"encoding/json"
 	"net/http"
+	"net/url"
+	"slices"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 
+	"practice5/filter"
 	"practice5/models"
 	"practice5/repository"
 )
 
+const (
+	// maxPageSize matches the GraphQL API's limit on first.
+	maxPageSize = 100
+	// maxPage bounds offset mode; deeper pages are cheaper with a cursor.
+	maxPage = 100_000
+)
+
 type Handler struct {
-	repo *repository.Repository
+	repo repository.UserStore
 }
 
-func New(repo *repository.Repository) *Handler {
+func New(repo repository.UserStore) *Handler {
 	return &Handler{repo: repo}
 }
 
 // GET /users
-// Query params: page, page_size, order_by, order_dir, id, name, email, gender, birthdate
+// Query params: page, page_size, sort, order_by, order_dir, id, name, email, gender, birthdate,
+// birthdate_from, birthdate_to, age_min, age_max, filter
+// id and gender accept comma-separated lists; filter takes an expression such as
+// gender = female AND (age >= 25 OR name ~ 'ali') — see package filter.
+// Passing cursor (empty for the first page) switches to keyset pagination;
+// follow next_cursor/prev_cursor from the response instead of page.
+// sort=gender,-birthdate,name orders by several columns ("-" for descending);
+// id is always the final tiebreaker.
+// fields=id,name returns only those columns; include=friend_count,friends
+// embeds each user's friend count and first friends_limit (default 5) friends.
+// Responses carry an ETag; a matching If-None-Match gets 304 Not Modified.
+// Soft-deleted users are left out unless an admin passes include_deleted=true.
 func (h *Handler) GetUsers(w http.ResponseWriter, r *http.Request) {
-	q := r.URL.Query()
+	params, err := parseFilterParams(r.URL.Query(), "include_deleted")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	var ok bool
+	if params.IncludeDeleted, ok = includeDeleted(w, r); !ok {
+		return
+	}
+	if err := repository.ValidateParams(params); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if h.notModified(w, r) {
+		return
+	}
+
+	result, err := h.repo.GetPaginatedUsers(r.Context(), params)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
 
-	page, _ := strconv.Atoi(q.Get("page"))
-	if page < 1 {
-		page = 1
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(shapePage(result, params))
+}
+
+// parseFilterParams reads the pagination, ordering and filter query params
+// shared by GET /users and GET /users/{id}/friends. Filter values that do
+// not parse are reported instead of being ignored, and so are params other
+// than these and the route's own extra ones.
+func parseFilterParams(q url.Values, extra ...string) (models.FilterParams, error) {
+	if err := rejectUnknownParams(q, filterParamNames, extra...); err != nil {
+		return models.FilterParams{}, err
+	}
+	page, err := rangeParam(q, "page", 1, 1, maxPage)
+	if err != nil {
+		return models.FilterParams{}, err
 	}
-	pageSize, _ := strconv.Atoi(q.Get("page_size"))
-	if pageSize < 1 {
-		pageSize = 10
+	pageSize, err := rangeParam(q, "page_size", 10, 1, maxPageSize)
+	if err != nil {
+		return models.FilterParams{}, err
 	}
 
 	params := models.FilterParams{
 		Page:     page,
 		PageSize: pageSize,
+		Sort:     q.Get("sort"),
 		OrderBy:  q.Get("order_by"),
 		OrderDir: q.Get("order_dir"),
 	}
 
 	if v := q.Get("id"); v != "" {
-		id, err := strconv.Atoi(v)
-		if err == nil {
-			params.ID = &id
+		for _, s := range strings.Split(v, ",") {
+			id, err := strconv.Atoi(strings.TrimSpace(s))
+			if err != nil {
+				return params, badParam("id", "%q is not an integer", s)
+			}
+			params.IDs = append(params.IDs, id)
 		}
 	}
 	if v := q.Get("name"); v != "" {
@@ -51,41 +112,175 @@ func (h *Handler) GetUsers(w http.ResponseWriter, r *http.Request) {
 		params.Email = &v
 	}
 	if v := q.Get("gender"); v != "" {
-		params.Gender = &v
+		for _, g := range strings.Split(v, ",") {
+			params.Genders = append(params.Genders, strings.TrimSpace(g))
+		}
+	}
+	for _, d := range []struct {
+		name string
+		dst  **string
+	}{
+		{"birthdate", &params.Birthdate},
+		{"birthdate_from", &params.BirthdateFrom},
+		{"birthdate_to", &params.BirthdateTo},
+	} {
+		name, dst := d.name, d.dst
+		if v := q.Get(name); v != "" {
+			if _, err := time.Parse("2006-01-02", v); err != nil {
+				return params, badParam(name, "%q is not a YYYY-MM-DD date", v)
+			}
+			*dst = &v
+		}
+	}
+	for _, d := range []struct {
+		name string
+		dst  **int
+	}{
+		{"age_min", &params.AgeMin},
+		{"age_max", &params.AgeMax},
+	} {
+		name, dst := d.name, d.dst
+		if v := q.Get(name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return params, badParam(name, "%q is not a non-negative integer", v)
+			}
+			*dst = &n
+		}
+	}
+	if v := q.Get("filter"); v != "" {
+		e, err := filter.Parse(v)
+		if err != nil {
+			return params, err
+		}
+		params.Filter = e
+	}
+	if q.Has("cursor") {
+		c := q.Get("cursor")
+		params.Cursor = &c
+	}
+	if err := parseFieldsAndIncludes(q, &params); err != nil {
+		return params, err
+	}
+	return params, nil
+}
+
+var (
+	filterParamNames        = paramNames(filterParams)
+	commonFriendsParamNames = paramNames(commonFriendsParams)
+)
+
+func paramNames(params []openAPIParam) map[string]bool {
+	names := make(map[string]bool, len(params))
+	for _, p := range params {
+		names[p.Name] = true
+	}
+	return names
+}
+
+// rejectUnknownParams reports the first query param, by name, that is
+// neither known nor extra, so a typo such as ?gendr=male is a 400 rather
+// than a filter silently left out.
+func rejectUnknownParams(q url.Values, known map[string]bool, extra ...string) error {
+	var unknown []string
+	for name := range q {
+		if !known[name] && !slices.Contains(extra, name) {
+			unknown = append(unknown, name)
+		}
 	}
-	if v := q.Get("birthdate"); v != "" {
-		params.Birthdate = &v
+	if len(unknown) == 0 {
+		return nil
 	}
+	sort.Strings(unknown)
+	return badParam(unknown[0], "unknown query parameter")
+}
 
-	result, err := h.repo.GetPaginatedUsers(params)
+// includeDeleted reads ?include_deleted, which only admins may set. Like
+// pathID it writes the error response itself.
+func includeDeleted(w http.ResponseWriter, r *http.Request) (include, ok bool) {
+	v := r.URL.Query().Get("include_deleted")
+	if v == "" {
+		return false, true
+	}
+	include, err := strconv.ParseBool(v)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		writeError(w, r, badParam("include_deleted", "%q is not a boolean", v))
+		return false, false
+	}
+	if include && !isAdmin(r) {
+		writeProblem(w, r, http.StatusForbidden, "forbidden", "include_deleted requires role "+RoleAdmin)
+		return false, false
 	}
+	return include, true
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(result)
+// intParam reads a required integer query param.
+func intParam(q url.Values, name string) (int, error) {
+	v := q.Get(name)
+	if v == "" {
+		return 0, badParam(name, "is required")
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, badParam(name, "%q is not an integer", v)
+	}
+	return n, nil
 }
 
+// rangeParam reads an optional integer query param that must lie in
+// [lo, hi]; hi = 0 means no upper bound.
+func rangeParam(q url.Values, name string, def, lo, hi int) (int, error) {
+	v := q.Get(name)
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	switch {
+	case err != nil:
+		return 0, badParam(name, "%q is not an integer", v)
+	case n < lo || (hi > 0 && n > hi):
+		if hi == 0 {
+			return 0, badParam(name, "must be at least %d", lo)
+		}
+		return 0, badParam(name, "must be between %d and %d", lo, hi)
+	}
+	return n, nil
+}
 
+// GET /users/common-friends?user1=1&user2=2
+// Supports If-None-Match and include_deleted like GET /users.
 func (h *Handler) GetCommonFriends(w http.ResponseWriter, r *http.Request) {
 	q := r.URL.Query()
 
-	user1, err1 := strconv.Atoi(q.Get("user1"))
-	user2, err2 := strconv.Atoi(q.Get("user2"))
-
-	if err1 != nil || err2 != nil {
-		http.Error(w, "user1 and user2 must be valid integers", http.StatusBadRequest)
+	if err := rejectUnknownParams(q, commonFriendsParamNames); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	user1, err := intParam(q, "user1")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	user2, err := intParam(q, "user2")
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
 	if user1 == user2 {
-		http.Error(w, "user1 and user2 must be different", http.StatusBadRequest)
+		writeError(w, r, badParam("user2", "must differ from user1"))
+		return
+	}
+	withDeleted, ok := includeDeleted(w, r)
+	if !ok {
+		return
+	}
+	if h.notModified(w, r) {
 		return
 	}
 
-	friends, err := h.repo.GetCommonFriends(user1, user2)
+	friends, err := h.repo.GetCommonFriends(r.Context(), user1, user2, withDeleted)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"practice5/cache"
+	"practice5/repository"
+)
+
+// instrumentStore reports the duration of every store call, labelled with
+// the method and the kind of error (ok, not_found, ..., internal).
+func instrumentStore(reg prometheus.Registerer, s repository.UserStore) repository.UserStore {
+	durations := promauto.With(reg).NewHistogramVec(prometheus.HistogramOpts{
+		Name: "store_query_duration_seconds", Help: "Duration of UserStore calls by method and result.",
+	}, []string{"method", "result"})
+	return repository.Instrument(s, func(method string, d time.Duration, err error) {
+		result := "ok"
+		if err != nil {
+			result = repository.KindOf(err).String()
+		}
+		durations.WithLabelValues(method, result).Observe(d.Seconds())
+	})
+}
+
+// cacheStore puts c in front of s and counts hits and misses per query.
+// It wraps the instrumented store, so store metrics only see cache misses.
+func cacheStore(reg prometheus.Registerer, s repository.UserStore, c cache.Cache) repository.UserStore {
+	lookups := promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+		Name: "cache_lookups_total", Help: "Cacheable store reads by query and result (hit or miss).",
+	}, []string{"query", "result"})
+	promauto.With(reg).NewGaugeFunc(prometheus.GaugeOpts{Name: "cache_entries", Help: "Entries currently cached."},
+		func() float64 { return float64(c.Len()) })
+	return repository.Cached(s, c, func(query string, hit bool) {
+		result := "miss"
+		if hit {
+			result = "hit"
+		}
+		lookups.WithLabelValues(query, result).Inc()
+	})
+}
+
+// registerDBStats exposes the connection pool counters of db.
+func registerDBStats(reg prometheus.Registerer, db *sql.DB) {
+	gauge := func(name, help string, fn func(s sql.DBStats) float64) {
+		promauto.With(reg).NewGaugeFunc(prometheus.GaugeOpts{Name: name, Help: help}, func() float64 { return fn(db.Stats()) })
+	}
+	counter := func(name, help string, fn func(s sql.DBStats) float64) {
+		promauto.With(reg).NewCounterFunc(prometheus.CounterOpts{Name: name, Help: help}, func() float64 { return fn(db.Stats()) })
+	}
+
+	gauge("db_connections_max_open", "Configured maximum of open connections (0 = unlimited).",
+		func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) })
+	gauge("db_connections_open", "Open connections, in use or idle.",
+		func(s sql.DBStats) float64 { return float64(s.OpenConnections) })
+	gauge("db_connections_in_use", "Connections currently running a query.",
+		func(s sql.DBStats) float64 { return float64(s.InUse) })
+	gauge("db_connections_idle", "Idle connections in the pool.",
+		func(s sql.DBStats) float64 { return float64(s.Idle) })
+	counter("db_connection_waits_total", "Times a query had to wait for a free connection.",
+		func(s sql.DBStats) float64 { return float64(s.WaitCount) })
+	counter("db_connection_wait_seconds_total", "Total time spent waiting for a free connection.",
+		func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() })
+	counter("db_connections_closed_max_idle_total", "Connections closed because the idle pool was full.",
+		func(s sql.DBStats) float64 { return float64(s.MaxIdleClosed) })
+	counter("db_connections_closed_max_lifetime_total", "Connections closed for exceeding conn_max_lifetime.",
+		func(s sql.DBStats) float64 { return float64(s.MaxLifetimeClosed) })
+}
@@ -1,27 +1,225 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"log"
 	"net/http"
+	"strings"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"practice5/analytics"
+	"practice5/cache"
 	"practice5/db"
+	"practice5/events"
+	"practice5/gql"
 	"practice5/handler"
+	"practice5/metrics"
+	"practice5/migrations"
 	"practice5/repository"
 )
 
+// Per-route query timeouts. Plain lookups should be instant; graph walks and
+// bulk transfers legitimately take longer.
+const (
+	queryTimeout         = 5 * time.Second
+	commonFriendsTimeout = 2 * time.Second
+	graphTimeout         = 10 * time.Second
+	importTimeout        = time.Minute
+	exportTimeout        = 10 * time.Minute
+)
+
+// eventsClientBuffer is how many events a GET /events client may fall
+// behind before it is disconnected.
+const eventsClientBuffer = 64
+
 func main() {
-	database := db.Connect()
-	defer database.Close()
+	store := flag.String("store", "postgres", "storage backend: postgres or memory (demo data, nothing persisted)")
+	seed := flag.Bool("seed", false, "load the demo users and friendships after migrating")
+	addr := flag.String("addr", ":8080", "listen address")
+	drainDelay := flag.Duration("drain-delay", 0, "after SIGTERM, how long /readyz fails before the listener closes")
+	shutdownTimeout := flag.Duration("shutdown-timeout", 30*time.Second, "how long in-flight requests may take to finish on shutdown")
+	cacheSize := flag.Int("cache-size", 1000, "cached list and common-friends results (0 disables the cache)")
+	cacheTTL := flag.Duration("cache-ttl", 5*time.Minute, "drop cached results after this long (0 = only on writes)")
+	graphRefresh := flag.Duration("graph-refresh", 5*time.Minute, "how old the snapshot behind /admin/graph may get before it is reloaded")
+	jwtSecret := flag.String("jwt-secret", "", "HS256 secret bearer tokens must be signed with (env JWT_SECRET)")
+	jwtPublicKey := flag.String("jwt-public-key", "", "PEM file with the RSA key RS256 bearer tokens are checked against (env JWT_PUBLIC_KEY_FILE)")
+	adminOpen := flag.Bool("admin-open", false, "serve /admin without authentication when no JWT key is configured")
+	purgeRetention := flag.Duration("purge-retention", 30*24*time.Hour, "hard-delete users this long after DELETE /users/{id} (0 keeps them forever)")
+	purgeInterval := flag.Duration("purge-interval", time.Hour, "how often the purge job looks for users past -purge-retention")
+	eventsBuffer := flag.Int("events-buffer", 1000, "recent events kept for Last-Event-ID replay on GET /events")
+	dbFlags := db.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+	ctx := context.Background()
+
+	verifier, err := loadVerifier(*jwtSecret, *jwtPublicKey)
+	if err != nil {
+		log.Fatal("JWT config: ", err)
+	}
+	if verifier == nil {
+		log.Println("⚠️  No JWT secret or public key configured; the API is open to everyone")
+		if *adminOpen {
+			log.Println("⚠️  -admin-open: /admin is open to everyone too")
+		}
+	}
+
+	var repo repository.UserStore
+	broker := events.NewBroker(*eventsBuffer, eventsClientBuffer)
+	health := handler.NewHealth()
+	reg := prometheus.DefaultRegisterer
+	switch *store {
+	case "memory":
+		if flag.Arg(0) == "migrate" {
+			log.Fatal("migrate needs --store=postgres")
+		}
+		mem := repository.NewMemoryStore()
+		if err := mem.LoadSeed(migrations.Seed); err != nil {
+			log.Fatal("Seeding failed: ", err)
+		}
+		log.Println("ℹ️  Using the in-memory store with demo data (GET /events stays silent)")
+		repo = mem
+
+	case "postgres":
+		cfg, err := dbFlags.Config()
+		if err != nil {
+			log.Fatal("Database config: ", err)
+		}
+		database, err := db.Connect(ctx, cfg)
+		if err != nil {
+			log.Fatal("Database connection failed: ", err)
+		}
+		defer database.Close()
+
+		migrator, err := db.NewMigrator(database, migrations.FS)
+		if err != nil {
+			log.Fatal("Loading migrations failed: ", err)
+		}
+
+		if flag.Arg(0) == "migrate" {
+			runMigrate(ctx, database, migrator, flag.Args()[1:])
+			return
+		}
 
-	repo := repository.New(database)
+		if _, err := migrator.Up(ctx); err != nil {
+			log.Fatal("Migration failed: ", err)
+		}
+		if *seed {
+			if err := db.Seed(database, migrations.Seed); err != nil {
+				log.Fatal("Seeding failed: ", err)
+			}
+		}
+		repo = repository.New(database)
+		health.AddCheck("database", database.PingContext)
+		health.AddCheck("migrations", migrator.Check)
+		registerDBStats(reg, database)
+
+		dsn, err := cfg.ConnString()
+		if err != nil {
+			log.Fatal("Database config: ", err)
+		}
+		go func() {
+			if err := events.Listen(ctx, dsn, broker); err != nil {
+				log.Println("⚠️  Events listener stopped, GET /events gets no more changes:", err)
+			}
+		}()
+
+	default:
+		log.Fatal("--store must be postgres or memory")
+	}
+
+	repo = instrumentStore(reg, repo)
+	if *cacheSize > 0 {
+		repo = cacheStore(reg, repo, cache.NewLRU(*cacheSize, *cacheTTL))
+	}
+	if *purgeRetention > 0 {
+		if *purgeInterval <= 0 {
+			log.Fatal("-purge-interval must be positive")
+		}
+		go purgeDeleted(ctx, reg, repo, *purgeRetention, *purgeInterval)
+	}
 	h := handler.New(repo)
+	graph := handler.NewAnalytics(analytics.NewService(repo.FriendGraph, *graphRefresh))
+	httpMetrics := metrics.NewHTTP(reg)
+	promauto.With(reg).NewGaugeFunc(prometheus.GaugeOpts{Name: "events_subscribers", Help: "Clients connected to GET /events."},
+		func() float64 { return float64(broker.Subscribers()) })
 
 	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", health.Liveness)
+	mux.HandleFunc("GET /readyz", health.Readiness)
+	mux.Handle("GET /metrics", promhttp.Handler())
+	mux.HandleFunc("GET /openapi.json", handler.OpenAPI)
+	mux.HandleFunc("GET /docs", handler.SwaggerUI)
+	mux.HandleFunc("GET /docs/{file}", handler.SwaggerUIAsset)
+
+	// Every route gets its own query budget (see handler.WithTimeout) and
+	// request metrics labelled with its pattern. With JWT configured, reads
+	// need any valid token; writes and /admin need role=admin. Without JWT,
+	// /admin stays closed unless -admin-open.
+	routeAs := func(role, pattern string, timeout time.Duration, fn http.HandlerFunc) {
+		mux.Handle(pattern, httpMetrics.Wrap(pattern, handler.RequireToken(verifier, role, handler.WithTimeout(timeout, fn))))
+	}
+	route := func(pattern string, timeout time.Duration, fn http.HandlerFunc) {
+		role := ""
+		if !strings.HasPrefix(pattern, "GET ") {
+			role = handler.RoleAdmin
+		}
+		routeAs(role, pattern, timeout, fn)
+	}
+	adminRoute := func(pattern string, timeout time.Duration, fn http.HandlerFunc) {
+		mux.Handle(pattern, httpMetrics.Wrap(pattern, handler.RequireAdmin(verifier, *adminOpen, handler.WithTimeout(timeout, fn))))
+	}
+
+	route("GET /users", queryTimeout, h.GetUsers)
+	route("POST /users", queryTimeout, h.CreateUser)
+	route("POST /users/import", importTimeout, h.ImportUsers)
+
+	route("GET /users/common-friends", commonFriendsTimeout, h.GetCommonFriends)
+	route("GET /users/path", graphTimeout, h.GetFriendPath)
+	route("GET /users/export", exportTimeout, h.ExportUsers)
+
+	route("GET /users/{id}", queryTimeout, h.GetUser)
+	route("PUT /users/{id}", queryTimeout, h.UpdateUser)
+	route("PATCH /users/{id}", queryTimeout, h.UpdateUser)
+	route("DELETE /users/{id}", queryTimeout, h.DeleteUser)
+	route("POST /users/{id}/restore", queryTimeout, h.RestoreUser)
+
+	route("GET /users/{id}/friends", queryTimeout, h.GetFriends)
+	route("GET /users/{id}/suggestions", graphTimeout, h.GetSuggestions)
+	route("DELETE /users/{id}/friends/{other}", queryTimeout, h.Unfriend)
+	route("GET /users/{id}/friend-requests", queryTimeout, h.GetFriendRequests)
+	route("POST /users/{id}/friend-requests/{other}", queryTimeout, h.SendFriendRequest)
+	route("DELETE /users/{id}/friend-requests/{other}", queryTimeout, h.CancelFriendRequest)
+	route("POST /users/{id}/friend-requests/{other}/accept", queryTimeout, h.AcceptFriendRequest)
+	route("POST /users/{id}/friend-requests/{other}/decline", queryTimeout, h.DeclineFriendRequest)
+
+	// The GraphQL schema has no mutations, so any valid token may POST to it.
+	routeAs("", "POST /graphql", graphTimeout, gql.NewHandler(repo).ServeHTTP)
 
-	mux.HandleFunc("/users", h.GetUsers)
+	// A stream has no query budget; it runs until the client leaves, falls
+	// behind or the server shuts down.
+	mux.Handle("GET /events", httpMetrics.Wrap("GET /events", handler.RequireToken(verifier, "", handler.NewEvents(broker).Stream)))
 
-	mux.HandleFunc("/users/common-friends", h.GetCommonFriends)
+	adminRoute("GET /admin/graph", graphTimeout, graph.Summary)
+	adminRoute("GET /admin/graph/components", graphTimeout, graph.Components)
+	adminRoute("GET /admin/graph/top-users", graphTimeout, graph.TopUsers)
+	adminRoute("GET /admin/graph/isolated", graphTimeout, graph.Isolated)
+	adminRoute("GET /admin/graph/users/{id}", graphTimeout, graph.User)
 
-	log.Println("🚀 Server running on http://localhost:8080")
-	log.Fatal(http.ListenAndServe(":8080", mux))
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           handler.RequestID(mux),
+		ReadHeaderTimeout: readHeaderTimeout,
+		ReadTimeout:       readTimeout,
+		WriteTimeout:      writeTimeout,
+		IdleTimeout:       idleTimeout,
+	}
+	srv.RegisterOnShutdown(broker.Close)
+	log.Printf("🚀 Server running on %s", *addr)
+	if err := serve(srv, health, *drainDelay, *shutdownTimeout); err != nil {
+		log.Fatal(err)
+	}
 }
@@ -0,0 +1,39 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestHTTPWrap(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m := NewHTTP(reg)
+	h := m.Wrap("GET /users/{id}", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := w.(http.Flusher); !ok {
+			t.Error("wrapped writer lost http.Flusher")
+		}
+		if r.PathValue("id") == "9" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	mux := http.NewServeMux()
+	mux.Handle("GET /users/{id}", h)
+	for _, path := range []string{"/users/1", "/users/2", "/users/9"} {
+		mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	for status, want := range map[string]float64{"200": 2, "404": 1} {
+		if got := testutil.ToFloat64(m.requests.WithLabelValues("GET", "/users/{id}", status)); got != want {
+			t.Errorf("http_requests_total{status=%q} = %v; want %v", status, got, want)
+		}
+	}
+	if n := testutil.CollectAndCount(m.duration); n != 2 {
+		t.Errorf("http_request_duration_seconds has %d series; want 2", n)
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"practice5/db"
+	"practice5/migrations"
+)
+
+const migrateUsage = "usage: practice5 migrate up | down [steps] | status | seed"
+
+// runMigrate implements `practice5 migrate ...`.
+func runMigrate(ctx context.Context, database *sql.DB, migrator *db.Migrator, args []string) {
+	if len(args) == 0 {
+		log.Fatal(migrateUsage)
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			log.Fatal("Migration failed: ", err)
+		}
+		if len(applied) == 0 {
+			log.Println("ℹ️  Schema is up to date")
+		}
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				log.Fatal("steps must be a positive integer")
+			}
+			steps = n
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		if err != nil {
+			log.Fatal("Migration failed: ", err)
+		}
+		if len(reverted) == 0 {
+			log.Println("ℹ️  Nothing to revert")
+		}
+
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			log.Fatal("Status failed: ", err)
+		}
+		applied := 0
+		for _, s := range statuses {
+			if s.AppliedAt != nil {
+				applied++
+			}
+		}
+		if applied == 0 {
+			fmt.Println("No migrations applied")
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "VERSION\tNAME\tAPPLIED AT")
+		for _, s := range statuses {
+			at := "pending"
+			if s.AppliedAt != nil {
+				at = s.AppliedAt.Format("2006-01-02 15:04:05 MST")
+			}
+			fmt.Fprintf(tw, "%04d\t%s\t%s\n", s.Version, s.Name, at)
+		}
+		tw.Flush()
+
+	case "seed":
+		if err := db.Seed(database, migrations.Seed); err != nil {
+			log.Fatal("Seeding failed: ", err)
+		}
+
+	default:
+		log.Fatal(migrateUsage)
+	}
+}
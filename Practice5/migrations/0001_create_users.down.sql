@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS user_friends;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,15 @@
+-- IF NOT EXISTS keeps this safe on databases created by the old db.Migrate.
+CREATE TABLE IF NOT EXISTS users (
+    id        SERIAL PRIMARY KEY,
+    name      VARCHAR(100) NOT NULL,
+    email     VARCHAR(100) UNIQUE NOT NULL,
+    gender    VARCHAR(10)  NOT NULL,
+    birthdate DATE         NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS user_friends (
+    user_id   INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    friend_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    PRIMARY KEY (user_id, friend_id),
+    CHECK (user_id <> friend_id)
+);
@@ -0,0 +1,11 @@
+-- Friend requests; accepting one writes both directed user_friends rows
+CREATE TABLE IF NOT EXISTS friend_requests (
+    requester_id INTEGER     NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    addressee_id INTEGER     NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status       VARCHAR(10) NOT NULL DEFAULT 'pending'
+                 CHECK (status IN ('pending', 'accepted', 'declined', 'cancelled')),
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (requester_id, addressee_id),
+    CHECK (requester_id <> addressee_id)
+);
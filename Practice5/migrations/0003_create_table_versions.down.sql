@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS user_friends_bump_version ON user_friends;
+DROP TRIGGER IF EXISTS users_bump_version ON users;
+DROP FUNCTION IF EXISTS bump_table_version();
+DROP TABLE IF EXISTS table_versions;
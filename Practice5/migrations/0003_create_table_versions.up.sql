@@ -0,0 +1,24 @@
+-- One counter per table, bumped once per writing statement. Listings use
+-- them in their ETags.
+CREATE TABLE IF NOT EXISTS table_versions (
+    table_name TEXT   PRIMARY KEY,
+    version    BIGINT NOT NULL DEFAULT 0
+);
+
+INSERT INTO table_versions (table_name) VALUES ('users'), ('user_friends')
+ON CONFLICT DO NOTHING;
+
+CREATE OR REPLACE FUNCTION bump_table_version() RETURNS trigger AS $$
+BEGIN
+    UPDATE table_versions SET version = version + 1 WHERE table_name = TG_TABLE_NAME;
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER users_bump_version
+    AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON users
+    FOR EACH STATEMENT EXECUTE FUNCTION bump_table_version();
+
+CREATE TRIGGER user_friends_bump_version
+    AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON user_friends
+    FOR EACH STATEMENT EXECUTE FUNCTION bump_table_version();
@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS user_friends_notify ON user_friends;
+DROP TRIGGER IF EXISTS users_notify_update ON users;
+DROP TRIGGER IF EXISTS users_notify_insert ON users;
+DROP FUNCTION IF EXISTS notify_friendship_change();
+DROP FUNCTION IF EXISTS notify_user_change();
@@ -0,0 +1,44 @@
+-- Row changes are announced on the practice5_events channel for GET /events.
+-- Payloads are JSON: {"type": "user.created", "user": {...}} or
+-- {"type": "friendship.added", "user_id": 1, "friend_id": 2}.
+CREATE OR REPLACE FUNCTION notify_user_change() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('practice5_events', json_build_object(
+        'type', CASE TG_OP WHEN 'INSERT' THEN 'user.created' ELSE 'user.updated' END,
+        'user', json_build_object(
+            'id', NEW.id, 'name', NEW.name, 'email', NEW.email,
+            'gender', NEW.gender, 'birthdate', NEW.birthdate)
+    )::text);
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+-- Friendships are stored in both directions; only the (low, high) row is
+-- announced so each change yields one event.
+CREATE OR REPLACE FUNCTION notify_friendship_change() RETURNS trigger AS $$
+DECLARE
+    r user_friends;
+BEGIN
+    IF TG_OP = 'INSERT' THEN r := NEW; ELSE r := OLD; END IF;
+    IF r.user_id < r.friend_id THEN
+        PERFORM pg_notify('practice5_events', json_build_object(
+            'type', CASE TG_OP WHEN 'INSERT' THEN 'friendship.added' ELSE 'friendship.removed' END,
+            'user_id', r.user_id,
+            'friend_id', r.friend_id
+        )::text);
+    END IF;
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER users_notify_insert
+    AFTER INSERT ON users
+    FOR EACH ROW EXECUTE FUNCTION notify_user_change();
+
+CREATE TRIGGER users_notify_update
+    AFTER UPDATE ON users
+    FOR EACH ROW WHEN (OLD IS DISTINCT FROM NEW) EXECUTE FUNCTION notify_user_change();
+
+CREATE TRIGGER user_friends_notify
+    AFTER INSERT OR DELETE ON user_friends
+    FOR EACH ROW EXECUTE FUNCTION notify_friendship_change();
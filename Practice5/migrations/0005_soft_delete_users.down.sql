@@ -0,0 +1,17 @@
+CREATE OR REPLACE FUNCTION notify_user_change() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('practice5_events', json_build_object(
+        'type', CASE TG_OP WHEN 'INSERT' THEN 'user.created' ELSE 'user.updated' END,
+        'user', json_build_object(
+            'id', NEW.id, 'name', NEW.name, 'email', NEW.email,
+            'gender', NEW.gender, 'birthdate', NEW.birthdate)
+    )::text);
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+-- Without the column, soft-deleted users would reappear; finish deleting them.
+DELETE FROM users WHERE deleted_at IS NOT NULL;
+
+DROP INDEX IF EXISTS users_deleted_at;
+ALTER TABLE users DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,18 @@
+-- DELETE /users/{id} now only sets deleted_at; friendships stay so that a
+-- restore brings them back. The purge job hard-deletes old rows.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS users_deleted_at ON users (deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- Same as in 0004, plus deleted_at, so GET /events shows deletes and restores.
+CREATE OR REPLACE FUNCTION notify_user_change() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('practice5_events', json_build_object(
+        'type', CASE TG_OP WHEN 'INSERT' THEN 'user.created' ELSE 'user.updated' END,
+        'user', json_build_object(
+            'id', NEW.id, 'name', NEW.name, 'email', NEW.email,
+            'gender', NEW.gender, 'birthdate', NEW.birthdate, 'deleted_at', NEW.deleted_at)
+    )::text);
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS friend_requests_one_pending_per_pair;
@@ -0,0 +1,15 @@
+-- At most one pending request per pair of users, whichever way it points,
+-- so concurrent A->B and B->A requests cannot both succeed. Should that
+-- already have happened, the newer of the two is cancelled first.
+UPDATE friend_requests f SET status = 'cancelled', updated_at = now()
+WHERE f.status = 'pending'
+  AND EXISTS (
+      SELECT 1 FROM friend_requests o
+      WHERE o.requester_id = f.addressee_id AND o.addressee_id = f.requester_id
+        AND o.status = 'pending'
+        AND (o.created_at, o.requester_id) < (f.created_at, f.requester_id)
+  );
+
+CREATE UNIQUE INDEX IF NOT EXISTS friend_requests_one_pending_per_pair
+    ON friend_requests (LEAST(requester_id, addressee_id), GREATEST(requester_id, addressee_id))
+    WHERE status = 'pending';
@@ -0,0 +1,16 @@
+CREATE OR REPLACE FUNCTION bump_table_version() RETURNS trigger AS $$
+BEGIN
+    UPDATE table_versions SET version = version + 1 WHERE table_name = TG_TABLE_NAME;
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+-- Fold the slots back into one row per table so versions keep growing.
+UPDATE table_versions t SET version = s.total
+FROM (SELECT table_name, SUM(version) AS total FROM table_versions GROUP BY table_name) s
+WHERE t.table_name = s.table_name AND t.slot = 0;
+DELETE FROM table_versions WHERE slot <> 0;
+
+ALTER TABLE table_versions DROP CONSTRAINT IF EXISTS table_versions_pkey;
+ALTER TABLE table_versions DROP COLUMN slot;
+ALTER TABLE table_versions ADD PRIMARY KEY (table_name);
@@ -0,0 +1,22 @@
+-- Every writing statement updated the same table_versions row, so
+-- concurrent writers queued on its row lock until the first one committed.
+-- Each table's counter is now spread over 64 slots, picked by backend pid:
+-- writers on different connections update different rows. DataVersion
+-- sums the slots, which still only moves once a write commits. (A sequence
+-- would not do: nextval is visible before the write commits, so a tag could
+-- name a version whose rows a reader cannot see yet.)
+ALTER TABLE table_versions ADD COLUMN IF NOT EXISTS slot INT NOT NULL DEFAULT 0;
+ALTER TABLE table_versions DROP CONSTRAINT IF EXISTS table_versions_pkey;
+ALTER TABLE table_versions ADD PRIMARY KEY (table_name, slot);
+
+INSERT INTO table_versions (table_name, slot)
+SELECT t, s FROM unnest(ARRAY['users', 'user_friends']) t, generate_series(1, 63) s
+ON CONFLICT DO NOTHING;
+
+CREATE OR REPLACE FUNCTION bump_table_version() RETURNS trigger AS $$
+BEGIN
+    UPDATE table_versions SET version = version + 1
+    WHERE table_name = TG_TABLE_NAME AND slot = pg_backend_pid() % 64;
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
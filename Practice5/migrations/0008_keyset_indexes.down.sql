@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS users_birthdate_id;
+DROP INDEX IF EXISTS users_gender_id;
+DROP INDEX IF EXISTS users_email_id;
+DROP INDEX IF EXISTS users_name_id;
@@ -0,0 +1,8 @@
+-- Keyset pagination orders by one column and then id (see sortKeys), and
+-- text in byte order (COLLATE "C"). These indexes let a cursor page start
+-- at its boundary row instead of sorting the table. Deleted users are
+-- hidden from listings by default, so only live rows are indexed.
+CREATE INDEX IF NOT EXISTS users_name_id      ON users (name COLLATE "C", id)   WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS users_email_id     ON users (email COLLATE "C", id)  WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS users_gender_id    ON users (gender COLLATE "C", id) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS users_birthdate_id ON users (birthdate, id)          WHERE deleted_at IS NULL;
@@ -0,0 +1,13 @@
+// Package migrations holds the numbered schema migrations applied by
+// db.Migrator and the optional demo seed.
+//
+// Files are named NNNN_description.up.sql / NNNN_description.down.sql.
+package migrations
+
+import "embed"
+
+//go:embed *.up.sql *.down.sql
+var FS embed.FS
+
+//go:embed seed.sql
+var Seed string
@@ -1,20 +1,3 @@
--- migrations/init.sql
-
-CREATE TABLE IF NOT EXISTS users (
-    id        SERIAL PRIMARY KEY,
-    name      VARCHAR(100) NOT NULL,
-    email     VARCHAR(100) UNIQUE NOT NULL,
-    gender    VARCHAR(10)  NOT NULL,
-    birthdate DATE         NOT NULL
-);
-
-CREATE TABLE IF NOT EXISTS user_friends (
-    user_id   INTEGER REFERENCES users(id) ON DELETE CASCADE,
-    friend_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
-    PRIMARY KEY (user_id, friend_id),
-    CHECK (user_id <> friend_id)
-);
-
 -- 20 users
 INSERT INTO users (name, email, gender, birthdate) VALUES
 ('Alice Johnson',  'alice@mail.com',   'female', '1995-03-12'),
@@ -38,6 +21,7 @@ INSERT INTO users (name, email, gender, birthdate) VALUES
 ('Sophia Lewis',   'sophia@mail.com',  'female', '1996-06-27'),
 ('Tom Walker',     'tom@mail.com',     'male',   '1994-08-01');
 
+-- Alice(1) and Bob(2) share 3 common friends: Carol(3), David(4), Eva(5)
 INSERT INTO user_friends (user_id, friend_id) VALUES
 (1, 3),(3, 1),
 (1, 4),(4, 1),
@@ -1,6 +1,11 @@
 package models
 
-import "time"
+import (
+	"strconv"
+	"time"
+
+	"practice5/filter"
+)
 
 type User struct {
 	ID        int       `json:"id"`
@@ -8,23 +13,156 @@ type User struct {
 	Email     string    `json:"email"`
 	Gender    string    `json:"gender"`
 	Birthdate time.Time `json:"birthdate"`
+	// DeletedAt is set while the user is soft-deleted, until it is restored
+	// or purged.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+
+	// Relations, filled in only when asked for with FilterParams.Include.
+	FriendCount *int   `json:"friend_count,omitempty"`
+	Friends     []User `json:"friends,omitempty"`
 }
 
+// PaginatedResponse is returned in both pagination modes. TotalCount and
+// Page are always set in offset mode, even to 0; in cursor mode they are
+// not computed, stay nil and are left out of the JSON.
 type PaginatedResponse struct {
 	Data       []User `json:"data"`
-	TotalCount int    `json:"total_count"`
-	Page       int    `json:"page"`
+	TotalCount *int   `json:"total_count,omitempty"`
+	Page       *int   `json:"page,omitempty"`
 	PageSize   int    `json:"page_size"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
 }
 
 type FilterParams struct {
-	ID        *int
-	Name      *string
-	Email     *string
-	Gender    *string
-	Birthdate *string // "YYYY-MM-DD"
-	OrderBy   string  // "id", "name", "email", "gender", "birthdate"
-	OrderDir  string  // "ASC" or "DESC"
-	Page      int
-	PageSize  int
+	IDs           []int
+	Name          *string
+	Email         *string
+	Genders       []string
+	Birthdate     *string // "YYYY-MM-DD"
+	BirthdateFrom *string // inclusive
+	BirthdateTo   *string // inclusive
+	AgeMin        *int
+	AgeMax        *int
+	Filter        filter.Expr // parsed ?filter= expression
+	Sort          string      // "gender,-birthdate,name"; takes precedence over OrderBy/OrderDir
+	OrderBy       string      // "id", "name", "email", "gender", "birthdate"
+	OrderDir      string      // "ASC" or "DESC"
+	Page          int
+	PageSize      int
+	Cursor        *string  // opaque keyset cursor; nil selects page/offset mode
+	FriendsOf     *int     // restricts the list to friends of this user
+	Fields        []string // columns needed, e.g. ["id", "name"]; others may come back zero. nil means all
+	Include       Include
+	// IncludeDeleted also lists soft-deleted users; they are hidden otherwise.
+	IncludeDeleted bool
+}
+
+// Include asks for relations embedded in each listed user. They are
+// loaded for the whole page in one query.
+type Include struct {
+	FriendCount bool
+	Friends     int // embed up to this many friends, lowest id first; 0 for none
+}
+
+// Expr combines every filter in p (except FriendsOf) into one AND
+// expression. Values are expected to be validated by the caller.
+func (p FilterParams) Expr() filter.And {
+	var and filter.And
+	add := func(field string, op filter.Op, values ...string) {
+		and = append(and, filter.Cond{Field: field, Op: op, Values: values})
+	}
+
+	if len(p.IDs) > 0 {
+		ids := make([]string, len(p.IDs))
+		for i, id := range p.IDs {
+			ids[i] = strconv.Itoa(id)
+		}
+		add("id", filter.In, ids...)
+	}
+	if p.Name != nil {
+		add("name", filter.Contains, *p.Name)
+	}
+	if p.Email != nil {
+		add("email", filter.Contains, *p.Email)
+	}
+	if len(p.Genders) > 0 {
+		add("gender", filter.In, p.Genders...)
+	}
+	if p.Birthdate != nil {
+		add("birthdate", filter.Eq, *p.Birthdate)
+	}
+	if p.BirthdateFrom != nil {
+		add("birthdate", filter.Ge, *p.BirthdateFrom)
+	}
+	if p.BirthdateTo != nil {
+		add("birthdate", filter.Le, *p.BirthdateTo)
+	}
+	if p.AgeMin != nil {
+		add("age", filter.Ge, strconv.Itoa(*p.AgeMin))
+	}
+	if p.AgeMax != nil {
+		add("age", filter.Le, strconv.Itoa(*p.AgeMax))
+	}
+	if p.Filter != nil {
+		and = append(and, p.Filter)
+	}
+	return and
+}
+
+// UserInput is the request body for POST/PUT/PATCH /users.
+// Nil fields are left unchanged by PATCH.
+type UserInput struct {
+	Name      *string `json:"name"`
+	Email     *string `json:"email"`
+	Gender    *string `json:"gender"`
+	Birthdate *string `json:"birthdate"` // "YYYY-MM-DD"
+}
+
+type FriendRequest struct {
+	RequesterID int       `json:"requester_id"`
+	AddresseeID int       `json:"addressee_id"`
+	Status      string    `json:"status"` // "pending", "accepted", "declined" or "cancelled"
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// FriendSuggestion is a friend-of-friend who is not yet a friend.
+type FriendSuggestion struct {
+	User
+	MutualCount   int      `json:"mutual_count"`
+	MutualFriends []string `json:"mutual_friends"` // names, a sample of at most N
+}
+
+// FriendPath is the shortest chain of friends between two users, both ends included.
+type FriendPath struct {
+	Users []User `json:"users"`
+	Hops  int    `json:"hops"`
+}
+
+// ImportRow is one parsed line of a POST /users/import body.
+type ImportRow struct {
+	Line  int
+	Input UserInput
+}
+
+type ImportError struct {
+	Line    int    `json:"line"`
+	Email   string `json:"email,omitempty"`
+	Message string `json:"message"`
+}
+
+type ImportResult struct {
+	Mode     string        `json:"mode"` // "all-or-nothing" or "best-effort"
+	Total    int           `json:"total"`
+	Imported int           `json:"imported"`
+	Failed   int           `json:"failed"`
+	Errors   []ImportError `json:"errors"`
+}
+
+// FriendGraph is a snapshot of the friendship graph: every user id, and
+// each friendship once as a [lower id, higher id] pair.
+type FriendGraph struct {
+	UserIDs []int
+	Edges   [][2]int
 }
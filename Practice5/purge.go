@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"practice5/repository"
+)
+
+// purgeTimeout bounds one purge run; like an import it may touch many rows.
+const purgeTimeout = time.Minute
+
+// purgeDeleted hard-deletes users soft-deleted more than retention ago,
+// right away and then every interval, until ctx is done. A failed run is
+// logged and retried on the next tick.
+func purgeDeleted(ctx context.Context, reg prometheus.Registerer, s repository.UserStore, retention, interval time.Duration) {
+	purged := promauto.With(reg).NewCounter(prometheus.CounterOpts{
+		Name: "users_purged_total", Help: "Soft-deleted users removed by the purge job.",
+	})
+
+	run := func() {
+		runCtx, cancel := context.WithTimeout(ctx, purgeTimeout)
+		defer cancel()
+		ids, err := s.PurgeDeletedUsers(runCtx, time.Now().Add(-retention))
+		if err != nil {
+			log.Println("⚠️  Purging deleted users failed:", err)
+			return
+		}
+		purged.Add(float64(len(ids)))
+		if len(ids) > 0 {
+			log.Printf("🧹 Purged %d users deleted more than %s ago", len(ids), retention)
+		}
+	}
+
+	run()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			run()
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"practice5/cache"
+	"practice5/models"
+)
+
+// CacheObserver is told whether each cacheable read was a hit. query is
+// "users", "friends" or "common_friends".
+type CacheObserver func(query string, hit bool)
+
+// Cached puts c in front of the list and common-friends reads of s. Writes
+// made through the returned store drop the entries they affect:
+//
+//   - "users" tags every list, since any user write can change which rows
+//     match, their order and the total;
+//   - "user:<id>" tags entries showing that user, so an update reaches the
+//     common-friends results it appears in;
+//   - "friends:<id>" tags entries built from that user's friend list.
+//
+// Writes that bypass the store, including other instances, are only seen
+// once entries expire; a hit costs no query, not even for DataVersion.
+func Cached(s UserStore, c cache.Cache, observe CacheObserver) UserStore {
+	return &cachedStore{UserStore: s, cache: c, observe: observe}
+}
+
+// cachedStore passes every method it does not override straight to the
+// embedded store.
+type cachedStore struct {
+	UserStore
+	cache   cache.Cache
+	observe CacheObserver
+
+	// A read that started before a write may still return the old data
+	// after the write invalidated its key. gen lets it notice and not
+	// store it; mu makes the check and the Set one step.
+	mu  sync.Mutex
+	gen uint64
+}
+
+const tagUsers = "users"
+
+func tagUser(id int) string    { return "user:" + strconv.Itoa(id) }
+func tagFriends(id int) string { return "friends:" + strconv.Itoa(id) }
+
+// read returns the cached value for key or loads, caches and returns it.
+// tags derives the entry's tags from the loaded value.
+func read[T any](s *cachedStore, query, key string, load func() (T, error), tags func(T) []string) (T, error) {
+	if v, ok := s.cache.Get(key); ok {
+		s.observe(query, true)
+		return v.(T), nil
+	}
+	s.observe(query, false)
+
+	s.mu.Lock()
+	gen := s.gen
+	s.mu.Unlock()
+
+	v, err := load()
+	if err != nil {
+		return v, err
+	}
+
+	s.mu.Lock()
+	if s.gen == gen {
+		s.cache.Set(key, v, tags(v))
+	}
+	s.mu.Unlock()
+	return v, nil
+}
+
+// invalidate runs after a write whether or not it failed: a timed-out
+// write may still have committed.
+func (s *cachedStore) invalidate(tags ...string) {
+	s.mu.Lock()
+	s.gen++
+	s.cache.Invalidate(tags...)
+	s.mu.Unlock()
+}
+
+// paramsKey identifies everything in p that affects the result. The filter
+// AST holds no pointers, so %#v spells it out unambiguously.
+func paramsKey(p models.FilterParams) string {
+	cursor, friendsOf := "-", "-"
+	if p.Cursor != nil {
+		cursor = strconv.Quote(*p.Cursor)
+	}
+	if p.FriendsOf != nil {
+		friendsOf = strconv.Itoa(*p.FriendsOf)
+	}
+	return fmt.Sprintf("%#v|%q|%q|%q|%d|%d|%s|%s|%q|%t|%d|%t",
+		p.Expr(), p.Sort, p.OrderBy, p.OrderDir, p.Page, p.PageSize,
+		cursor, friendsOf, p.Fields, p.Include.FriendCount, p.Include.Friends, p.IncludeDeleted)
+}
+
+// pageTags tags a page with "users", plus the friend lists its embedded
+// relations were built from.
+func pageTags(p models.FilterParams, extra ...string) func(models.PaginatedResponse) []string {
+	return func(page models.PaginatedResponse) []string {
+		tags := append([]string{tagUsers}, extra...)
+		if p.FriendsOf != nil {
+			tags = append(tags, tagFriends(*p.FriendsOf))
+		}
+		if p.Include.FriendCount || p.Include.Friends > 0 {
+			for _, u := range page.Data {
+				tags = append(tags, tagFriends(u.ID))
+			}
+		}
+		return tags
+	}
+}
+
+func (s *cachedStore) GetPaginatedUsers(ctx context.Context, p models.FilterParams) (models.PaginatedResponse, error) {
+	return read(s, "users", "users|"+paramsKey(p),
+		func() (models.PaginatedResponse, error) { return s.UserStore.GetPaginatedUsers(ctx, p) },
+		pageTags(p))
+}
+
+func (s *cachedStore) GetFriends(ctx context.Context, userID int, p models.FilterParams) (models.PaginatedResponse, error) {
+	return read(s, "friends", "friends|"+strconv.Itoa(userID)+"|"+paramsKey(p),
+		func() (models.PaginatedResponse, error) { return s.UserStore.GetFriends(ctx, userID, p) },
+		pageTags(p, tagFriends(userID)))
+}
+
+// GetCommonFriends is symmetric, so (a, b) and (b, a) share an entry.
+func (s *cachedStore) GetCommonFriends(ctx context.Context, userID1, userID2 int, includeDeleted bool) ([]models.User, error) {
+	a, b := min(userID1, userID2), max(userID1, userID2)
+	return read(s, "common_friends", fmt.Sprintf("common|%d|%d|%t", a, b, includeDeleted),
+		func() ([]models.User, error) {
+			return s.UserStore.GetCommonFriends(ctx, userID1, userID2, includeDeleted)
+		},
+		func(users []models.User) []string {
+			tags := []string{tagFriends(a), tagFriends(b)}
+			for _, u := range users {
+				tags = append(tags, tagUser(u.ID))
+			}
+			return tags
+		})
+}
+
+func (s *cachedStore) CreateUser(ctx context.Context, in models.UserInput) (models.User, error) {
+	defer s.invalidate(tagUsers)
+	return s.UserStore.CreateUser(ctx, in)
+}
+
+func (s *cachedStore) UpdateUser(ctx context.Context, id int, in models.UserInput) (models.User, error) {
+	defer s.invalidate(tagUsers, tagUser(id))
+	return s.UserStore.UpdateUser(ctx, id, in)
+}
+
+// DeleteUser hides the user from every list, its friends' lists included,
+// and those are all tagged "users"; common-friends results the user
+// appeared in carry its user tag.
+func (s *cachedStore) DeleteUser(ctx context.Context, id int) error {
+	defer s.invalidate(tagUsers, tagUser(id), tagFriends(id))
+	return s.UserStore.DeleteUser(ctx, id)
+}
+
+// RestoreUser must also reach the common-friends results computed while
+// the user was hidden. Those are tagged with the friend lists of the pair
+// asked about, and every such pair consists of friends of the user.
+func (s *cachedStore) RestoreUser(ctx context.Context, id int) (models.User, error) {
+	lists, err := s.UserStore.FriendLists(ctx, []int{id})
+	if err != nil {
+		return models.User{}, err
+	}
+	tags := []string{tagUsers, tagUser(id), tagFriends(id)}
+	for _, f := range lists[id] {
+		tags = append(tags, tagFriends(f.ID))
+	}
+	defer s.invalidate(tags...)
+	return s.UserStore.RestoreUser(ctx, id)
+}
+
+// PurgeDeletedUsers ends the purged users' friendships, like a hard
+// delete used to. A failed purge may still have committed, so it drops at
+// least the lists.
+func (s *cachedStore) PurgeDeletedUsers(ctx context.Context, cutoff time.Time) ([]int, error) {
+	ids, err := s.UserStore.PurgeDeletedUsers(ctx, cutoff)
+	if len(ids) > 0 || err != nil {
+		tags := []string{tagUsers}
+		for _, id := range ids {
+			tags = append(tags, tagUser(id), tagFriends(id))
+		}
+		s.invalidate(tags...)
+	}
+	return ids, err
+}
+
+func (s *cachedStore) AcceptFriendRequest(ctx context.Context, from, to int) error {
+	defer s.invalidate(tagFriends(from), tagFriends(to))
+	return s.UserStore.AcceptFriendRequest(ctx, from, to)
+}
+
+func (s *cachedStore) Unfriend(ctx context.Context, userID, friendID int) error {
+	defer s.invalidate(tagFriends(userID), tagFriends(friendID))
+	return s.UserStore.Unfriend(ctx, userID, friendID)
+}
+
+func (s *cachedStore) ImportUsers(ctx context.Context, rows []models.ImportRow, allOrNothing bool) (int, []models.ImportError, error) {
+	defer s.invalidate(tagUsers)
+	return s.UserStore.ImportUsers(ctx, rows, allOrNothing)
+}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"sort"
+	"testing"
+
+	"practice5/cache"
+	"practice5/migrations"
+	"practice5/models"
+)
+
+func TestCachedStore(t *testing.T) {
+	ctx := context.Background()
+	mem := NewMemoryStore()
+	if err := mem.LoadSeed(migrations.Seed); err != nil {
+		t.Fatal(err)
+	}
+	lookups := map[string]int{}
+	s := Cached(mem, cache.NewLRU(100, 0), func(query string, hit bool) {
+		if hit {
+			lookups[query+" hit"]++
+		} else {
+			lookups[query+" miss"]++
+		}
+	})
+
+	common := func(a, b int) []int {
+		t.Helper()
+		users, err := s.GetCommonFriends(ctx, a, b, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids := userIDs(users)
+		sort.Ints(ids)
+		return ids
+	}
+	list := func() models.PaginatedResponse {
+		t.Helper()
+		page, err := s.GetPaginatedUsers(ctx, models.FilterParams{Page: 1, PageSize: 3, Include: models.Include{FriendCount: true}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return page
+	}
+
+	// (1, 2) and (2, 1) share one entry.
+	if got := common(1, 2); !reflect.DeepEqual(got, []int{3, 4, 5}) {
+		t.Fatalf("common friends of 1 and 2 = %v", got)
+	}
+	common(2, 1)
+	count := *list().Data[0].FriendCount
+	list()
+	want := map[string]int{"common_friends miss": 1, "common_friends hit": 1, "users miss": 1, "users hit": 1}
+	if !reflect.DeepEqual(lookups, want) {
+		t.Errorf("lookups = %v; want %v", lookups, want)
+	}
+
+	// Unfriending 1 and 3 changes (1, 2) and user 1's friend count, but
+	// leaves lists without relations alone.
+	plain := models.FilterParams{Page: 1, PageSize: 3}
+	s.GetPaginatedUsers(ctx, plain)
+	if err := s.Unfriend(ctx, 1, 3); err != nil {
+		t.Fatal(err)
+	}
+	if got := common(2, 1); !reflect.DeepEqual(got, []int{4, 5}) {
+		t.Errorf("common friends after unfriend = %v; want [4 5]", got)
+	}
+	if got := *list().Data[0].FriendCount; got != count-1 {
+		t.Errorf("friend count of 1 after unfriend = %d; want %d", got, count-1)
+	}
+	before := lookups["users hit"]
+	s.GetPaginatedUsers(ctx, plain)
+	if lookups["users hit"] != before+1 {
+		t.Error("plain list was invalidated by a friendship change")
+	}
+
+	// Renaming a common friend reaches the cached common-friends result.
+	if _, err := s.UpdateUser(ctx, 4, models.UserInput{Name: strp("Dave")}); err != nil {
+		t.Fatal(err)
+	}
+	users, err := s.GetCommonFriends(ctx, 1, 2, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, u := range users {
+		if u.ID == 4 && u.Name != "Dave" {
+			t.Errorf("common friend 4 still named %q", u.Name)
+		}
+	}
+
+	// Deleting and restoring a common friend reaches the cached result both
+	// ways, although the restored user was not part of it.
+	if err := s.DeleteUser(ctx, 4); err != nil {
+		t.Fatal(err)
+	}
+	if got := common(1, 2); slices.Contains(got, 4) {
+		t.Errorf("common friends after deleting 4 = %v", got)
+	}
+	if _, err := s.RestoreUser(ctx, 4); err != nil {
+		t.Fatal(err)
+	}
+	if got := common(1, 2); !slices.Contains(got, 4) {
+		t.Errorf("common friends after restoring 4 = %v", got)
+	}
+}
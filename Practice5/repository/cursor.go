@@ -0,0 +1,192 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"practice5/models"
+)
+
+var ErrInvalidCursor = &Error{Kind: KindInvalid, Code: "invalid_cursor", Message: "invalid cursor", Field: "cursor"}
+
+// sortKey is one column of the ORDER BY used for keyset pagination.
+type sortKey struct {
+	col  string
+	desc bool
+}
+
+// cursor is the decoded form of the opaque ?cursor= value.
+// Values holds the sort key values of the boundary row, id last.
+type cursor struct {
+	Sort     string   `json:"s"`
+	Values   []string `json:"v"`
+	Backward bool     `json:"b,omitempty"`
+}
+
+func sortSignature(keys []sortKey) string {
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		if k.desc {
+			parts[i] = "-" + k.col
+		} else {
+			parts[i] = k.col
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+func encodeCursor(c cursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string, keys []sortKey) (cursor, error) {
+	var c cursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(b, &c); err != nil {
+		return c, ErrInvalidCursor
+	}
+	if c.Sort != sortSignature(keys) || len(c.Values) != len(keys) {
+		return c, detail(ErrInvalidCursor, "cursor", "does not match the requested sort order")
+	}
+	for i, k := range keys {
+		if !validKeyValue(k.col, c.Values[i]) {
+			return c, detail(ErrInvalidCursor, "cursor", "holds an invalid %s", k.col)
+		}
+	}
+	return c, nil
+}
+
+// validKeyValue reports whether v can stand for a value of col, so that a
+// forged cursor is a 400 here rather than a type error from the database.
+func validKeyValue(col, v string) bool {
+	switch col {
+	case "id":
+		_, err := strconv.ParseInt(v, 10, 32)
+		return err == nil
+	case "birthdate":
+		_, err := time.Parse("2006-01-02", v)
+		return err == nil
+	}
+	return utf8.ValidString(v) && !strings.ContainsRune(v, 0)
+}
+
+// cursorFor builds the cursor pointing at u in the given direction.
+func cursorFor(u models.User, keys []sortKey, backward bool) string {
+	vals := make([]string, len(keys))
+	for i, k := range keys {
+		vals[i] = columnValue(u, k.col)
+	}
+	return encodeCursor(cursor{Sort: sortSignature(keys), Values: vals, Backward: backward})
+}
+
+func columnValue(u models.User, col string) string {
+	switch col {
+	case "id":
+		return strconv.Itoa(u.ID)
+	case "name":
+		return u.Name
+	case "email":
+		return u.Email
+	case "gender":
+		return u.Gender
+	case "birthdate":
+		return u.Birthdate.Format("2006-01-02")
+	}
+	return ""
+}
+
+// keysetClause returns the predicate selecting rows strictly after (or,
+// when backward, strictly before) vals in the order described by keys:
+//
+//	(k1 > v1) OR (k1 = v1 AND k2 > v2) OR ...
+//
+// The expanded form is used instead of a row comparison so that every key
+// may have its own direction.
+func keysetClause(keys []sortKey, vals []string, backward bool, argIdx int) (string, []interface{}) {
+	var ors []string
+	var args []interface{}
+	for i, k := range keys {
+		var ands []string
+		for j := 0; j < i; j++ {
+			ands = append(ands, fmt.Sprintf("%s = $%d", collated(keys[j].col), argIdx))
+			args = append(args, vals[j])
+			argIdx++
+		}
+		op := ">"
+		if k.desc != backward {
+			op = "<"
+		}
+		ands = append(ands, fmt.Sprintf("%s %s $%d", collated(k.col), op, argIdx))
+		args = append(args, vals[i])
+		argIdx++
+		ors = append(ors, "("+strings.Join(ands, " AND ")+")")
+	}
+	return "(" + strings.Join(ors, " OR ") + ")", args
+}
+
+// offsetPage builds an offset-mode page. total_count and page are always
+// sent in this mode, so an empty page still reports 0 and its number.
+func offsetPage(users []models.User, total, page, pageSize int) models.PaginatedResponse {
+	if users == nil {
+		users = []models.User{}
+	}
+	return models.PaginatedResponse{Data: users, TotalCount: &total, Page: &page, PageSize: pageSize}
+}
+
+// pageOffset returns the first row of page, or false when the page starts
+// past the last of total rows. It divides rather than multiplies so that a
+// huge page number cannot overflow into a negative offset.
+func pageOffset(page, pageSize, total int) (int, bool) {
+	if page-1 > (total-1)/pageSize {
+		return 0, false
+	}
+	return (page - 1) * pageSize, true
+}
+
+// cursorPage turns up to pageSize+1 rows, fetched in cursor direction,
+// into a page in natural order with its next/prev cursors.
+func cursorPage(users []models.User, keys []sortKey, c cursor, hasCursor bool, pageSize int) models.PaginatedResponse {
+	hasMore := len(users) > pageSize
+	if hasMore {
+		users = users[:pageSize]
+	}
+	if c.Backward {
+		for i, j := 0, len(users)-1; i < j; i, j = i+1, j-1 {
+			users[i], users[j] = users[j], users[i]
+		}
+	}
+
+	resp := models.PaginatedResponse{Data: users, PageSize: pageSize}
+	if len(users) == 0 {
+		return resp
+	}
+	first, last := users[0], users[len(users)-1]
+	if hasMore || c.Backward {
+		resp.NextCursor = cursorFor(last, keys, false)
+	}
+	if (hasMore && c.Backward) || (hasCursor && !c.Backward) {
+		resp.PrevCursor = cursorFor(first, keys, true)
+	}
+	return resp
+}
+
+func orderByClause(keys []sortKey, backward bool) string {
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		dir := "ASC"
+		if k.desc != backward {
+			dir = "DESC"
+		}
+		parts[i] = collated(k.col) + " " + dir
+	}
+	return strings.Join(parts, ", ")
+}
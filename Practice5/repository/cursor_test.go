@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"practice5/models"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	keys := []sortKey{{col: "birthdate", desc: true}, {col: "id", desc: true}}
+	u := models.User{ID: 7, Birthdate: time.Date(1999, 4, 25, 0, 0, 0, 0, time.UTC)}
+
+	c, err := decodeCursor(cursorFor(u, keys, true), keys)
+	if err != nil {
+		t.Fatalf("decodeCursor: %v", err)
+	}
+	want := cursor{Sort: "-birthdate,-id", Values: []string{"1999-04-25", "7"}, Backward: true}
+	if !reflect.DeepEqual(c, want) {
+		t.Errorf("got %+v; want %+v", c, want)
+	}
+}
+
+func TestDecodeCursorRejects(t *testing.T) {
+	keys := []sortKey{{col: "name"}, {col: "id"}}
+	other := []sortKey{{col: "email"}, {col: "id"}}
+
+	tests := []struct {
+		name string
+		raw  string
+	}{
+		{"not base64", "!!!"},
+		{"not json", "bm9wZQ"},
+		{"different order", cursorFor(models.User{ID: 1}, other, false)},
+		{"id not a number", encodeCursor(cursor{Sort: "name,id", Values: []string{"Bob", "abc"}})},
+		{"id out of range", encodeCursor(cursor{Sort: "name,id", Values: []string{"Bob", "99999999999"}})},
+		{"NUL in text", encodeCursor(cursor{Sort: "name,id", Values: []string{"B\x00b", "2"}})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodeCursor(tt.raw, keys); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("decodeCursor(%q) error = %v; want ErrInvalidCursor", tt.raw, err)
+			}
+		})
+	}
+}
+
+func TestKeysetClause(t *testing.T) {
+	keys := []sortKey{{col: "name"}, {col: "id"}}
+
+	tests := []struct {
+		name     string
+		backward bool
+		want     string
+	}{
+		{"forward", false, `((name COLLATE "C" > $3) OR (name COLLATE "C" = $4 AND id > $5))`},
+		{"backward", true, `((name COLLATE "C" < $3) OR (name COLLATE "C" = $4 AND id < $5))`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, args := keysetClause(keys, []string{"Bob", "2"}, tt.backward, 3)
+			if got != tt.want {
+				t.Errorf("clause = %q; want %q", got, tt.want)
+			}
+			if want := []interface{}{"Bob", "Bob", "2"}; !reflect.DeepEqual(args, want) {
+				t.Errorf("args = %v; want %v", args, want)
+			}
+		})
+	}
+}
+
+func TestDecodeCursorRejectsBadDate(t *testing.T) {
+	keys := []sortKey{{col: "birthdate"}, {col: "id"}}
+	raw := encodeCursor(cursor{Sort: "birthdate,id", Values: []string{"x", "1"}})
+	if _, err := decodeCursor(raw, keys); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("decodeCursor error = %v; want ErrInvalidCursor", err)
+	}
+}
@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"practice5/filter"
+)
+
+// Kind classifies repository errors so callers can map them to a response
+// without knowing every individual error.
+type Kind int
+
+const (
+	KindInternal Kind = iota // anything unexpected, e.g. a lost connection
+	KindNotFound
+	KindConflict
+	KindInvalid // bad filter, sort or cursor
+	KindTimeout
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindNotFound:
+		return "not_found"
+	case KindConflict:
+		return "conflict"
+	case KindInvalid:
+		return "invalid"
+	case KindTimeout:
+		return "timeout"
+	}
+	return "internal"
+}
+
+// Error is an expected failure. Code is stable and safe to show to clients;
+// Err, the underlying cause, is not.
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	Field   string // the filter field or parameter at fault, if any
+	Err     error
+}
+
+func (e *Error) Error() string { return e.Message }
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// Is matches any *Error with the same Code, so a detailed error still
+// satisfies errors.Is against the sentinel it was built from.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// KindOf reports the kind of err, or KindInternal if it is not an *Error.
+func KindOf(err error) Kind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	return KindInternal
+}
+
+var (
+	ErrInvalidFilter = &Error{Kind: KindInvalid, Code: "invalid_filter", Message: "invalid filter"}
+	ErrTimeout       = &Error{Kind: KindTimeout, Code: "timeout", Message: "the query took too long and was cancelled"}
+)
+
+// detail returns a copy of sentinel with a more specific message.
+func detail(sentinel *Error, field, format string, args ...interface{}) *Error {
+	e := *sentinel
+	e.Field = field
+	e.Message = sentinel.Message + ": " + fmt.Sprintf(format, args...)
+	return &e
+}
+
+// checkFilter rejects conditions that did not come through filter.NewCond,
+// e.g. FilterParams assembled in code rather than parsed from a request.
+func checkFilter(e filter.Expr) error {
+	switch e := e.(type) {
+	case filter.And:
+		return checkFilters(e)
+	case filter.Or:
+		return checkFilters(e)
+	case filter.Not:
+		return checkFilter(e.X)
+	case filter.Cond:
+		if _, err := filter.NewCond(e.Field, e.Op, e.Values...); err != nil {
+			var fe *filter.FieldError
+			if errors.As(err, &fe) {
+				return detail(ErrInvalidFilter, fe.Field, "%s", fe.Msg)
+			}
+			return detail(ErrInvalidFilter, e.Field, "%v", err)
+		}
+	}
+	return nil
+}
+
+func checkFilters(es []filter.Expr) error {
+	for _, e := range es {
+		if err := checkFilter(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// classify turns a failure caused by ctx running out into ErrTimeout. It is
+// deferred by every store method that talks to the database:
+//
+//	defer classify(ctx, &err)
+//
+// The driver reports a cancelled query in its own words, so ctx decides.
+// Client disconnects (context.Canceled) are left alone.
+func classify(ctx context.Context, err *error) {
+	if *err == nil || KindOf(*err) != KindInternal {
+		return
+	}
+	if errors.Is(*err, context.DeadlineExceeded) || errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		e := *ErrTimeout
+		e.Err = *err
+		*err = &e
+	}
+}
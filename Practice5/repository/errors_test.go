@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestErrorIs(t *testing.T) {
+	err := detail(ErrInvalidSort, "sort", "column %q listed twice", "name")
+	if !errors.Is(err, ErrInvalidSort) || errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("detailed error should match its sentinel only")
+	}
+	if got, want := err.Error(), `invalid sort: column "name" listed twice`; got != want {
+		t.Errorf("Error() = %q; want %q", got, want)
+	}
+	if KindOf(err) != KindInvalid || KindOf(errors.New("boom")) != KindInternal {
+		t.Errorf("KindOf misclassified")
+	}
+}
+
+func TestClassify(t *testing.T) {
+	expired, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	gone, cancel := context.WithCancel(context.Background())
+	cancel()
+	driverErr := errors.New("pq: canceling statement due to user request")
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		err  error
+		want error // nil means unchanged
+	}{
+		{"deadline", expired, driverErr, ErrTimeout},
+		{"deadline error without ctx", context.Background(), context.DeadlineExceeded, ErrTimeout},
+		{"client gone", gone, driverErr, nil},
+		{"expected error kept", expired, ErrNotFound, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.err
+			classify(tt.ctx, &err)
+			want := tt.want
+			if want == nil {
+				want = tt.err
+			}
+			if !errors.Is(err, want) {
+				t.Errorf("classify(%v) = %v; want %v", tt.err, err, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"practice5/models"
+)
+
+const exportBatchSize = 1000
+
+// ExportUsers walks every user matching p's filters in p's sort order and
+// hands them to fn one batch at a time. Batches are fetched with keyset
+// queries, so memory use stays at one batch however large the result is.
+// Pagination fields of p are ignored.
+func (r *Repository) ExportUsers(ctx context.Context, p models.FilterParams, fn func(batch []models.User) error) (err error) {
+	defer classify(ctx, &err)
+	keys, err := checkParams(p)
+	if err != nil {
+		return err
+	}
+	baseClauses, baseArgs := buildFilters(p)
+	orderBy := orderByClause(keys, false)
+
+	var after []string
+	for {
+		whereClauses := append([]string{}, baseClauses...)
+		args := append([]interface{}{}, baseArgs...)
+		if after != nil {
+			clause, keyArgs := keysetClause(keys, after, false, len(args)+1)
+			whereClauses = append(whereClauses, clause)
+			args = append(args, keyArgs...)
+		}
+		where := ""
+		if len(whereClauses) > 0 {
+			where = "WHERE " + strings.Join(whereClauses, " AND ")
+		}
+
+		batch, err := r.queryUsers(ctx, fmt.Sprintf(
+			`SELECT id, name, email, gender, birthdate FROM users %s ORDER BY %s LIMIT $%d`,
+			where, orderBy, len(args)+1,
+		), append(args, exportBatchSize)...)
+		if err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := fn(batch); err != nil {
+			return err
+		}
+		if len(batch) < exportBatchSize {
+			return nil
+		}
+
+		last := batch[len(batch)-1]
+		after = make([]string, len(keys))
+		for i, k := range keys {
+			after[i] = columnValue(last, k.col)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"practice5/filter"
+	"practice5/models"
+)
+
+// matchFilter evaluates e against u with the same semantics compileFilter
+// gives the SQL: "=" is case-sensitive, "~" is a case-insensitive contains
+// that takes "%" and "_" literally, and age is the number of full years
+// since birthdate.
+func matchFilter(e filter.Expr, u models.User) bool {
+	switch e := e.(type) {
+	case filter.And:
+		for _, x := range e {
+			if !matchFilter(x, u) {
+				return false
+			}
+		}
+		return true
+	case filter.Or:
+		for _, x := range e {
+			if matchFilter(x, u) {
+				return true
+			}
+		}
+		return false
+	case filter.Not:
+		return !matchFilter(e.X, u)
+	case filter.Cond:
+		return matchCond(e, u)
+	}
+	return true
+}
+
+func matchCond(c filter.Cond, u models.User) bool {
+	var actual string
+	switch c.Field {
+	case "age":
+		actual = strconv.Itoa(ageOn(u.Birthdate, time.Now()))
+	default:
+		actual = columnValue(u, c.Field)
+	}
+	kind := filter.Fields[c.Field]
+
+	switch c.Op {
+	case filter.Contains:
+		return strings.Contains(strings.ToLower(actual), strings.ToLower(c.Values[0]))
+	case filter.In:
+		for _, v := range c.Values {
+			if compareValues(kind, actual, v) == 0 {
+				return true
+			}
+		}
+		return false
+	}
+
+	cmp := compareValues(kind, actual, c.Values[0])
+	switch c.Op {
+	case filter.Eq:
+		return cmp == 0
+	case filter.Ne:
+		return cmp != 0
+	case filter.Lt:
+		return cmp < 0
+	case filter.Le:
+		return cmp <= 0
+	case filter.Gt:
+		return cmp > 0
+	case filter.Ge:
+		return cmp >= 0
+	}
+	return false
+}
+
+// compareValues orders two values of kind; dates compare correctly as
+// YYYY-MM-DD strings. Text compares byte by byte, which is what the SQL
+// side gets from COLLATE "C" (see collated).
+func compareValues(kind filter.Kind, a, b string) int {
+	if kind == filter.Int {
+		x, _ := strconv.Atoi(a)
+		y, _ := strconv.Atoi(b)
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(a, b)
+}
+
+func ageOn(birthdate, now time.Time) int {
+	years := now.Year() - birthdate.Year()
+	if now.Month() < birthdate.Month() || (now.Month() == birthdate.Month() && now.Day() < birthdate.Day()) {
+		years--
+	}
+	return years
+}
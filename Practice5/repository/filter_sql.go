@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+
+	"practice5/filter"
+)
+
+// filterColumns maps filter fields to SQL expressions over users.
+var filterColumns = map[string]string{
+	"id":        "id",
+	"name":      "name",
+	"email":     "email",
+	"gender":    "gender",
+	"birthdate": "birthdate",
+	"age":       "date_part('year', age(birthdate))",
+}
+
+// textColumns are compared and sorted with COLLATE "C", byte by byte, so
+// that Postgres orders text exactly like the memory store's
+// strings.Compare whatever the database's default collation is.
+var textColumns = map[string]bool{"name": true, "email": true, "gender": true}
+
+// collated returns col as used in comparisons and ORDER BY.
+func collated(col string) string {
+	if textColumns[col] {
+		return col + ` COLLATE "C"`
+	}
+	return col
+}
+
+// likeEscaper makes "%", "_" and backslash match themselves in a LIKE
+// pattern with ESCAPE '\'.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// compileFilter turns e into a parameterized predicate whose placeholders
+// start at $argIdx. It returns "" for an empty expression.
+func compileFilter(e filter.Expr, argIdx int) (string, []interface{}) {
+	c := &sqlCompiler{argIdx: argIdx}
+	return c.compile(e), c.args
+}
+
+type sqlCompiler struct {
+	argIdx int
+	args   []interface{}
+}
+
+func (c *sqlCompiler) param(v interface{}) string {
+	c.args = append(c.args, v)
+	c.argIdx++
+	return fmt.Sprintf("$%d", c.argIdx-1)
+}
+
+func (c *sqlCompiler) compile(e filter.Expr) string {
+	switch e := e.(type) {
+	case filter.And:
+		return c.join(e, " AND ")
+	case filter.Or:
+		return c.join(e, " OR ")
+	case filter.Not:
+		return "NOT (" + c.compile(e.X) + ")"
+	case filter.Cond:
+		col := filterColumns[e.Field]
+		switch e.Op {
+		case filter.In:
+			ps := make([]string, len(e.Values))
+			for i, v := range e.Values {
+				ps[i] = c.param(v)
+			}
+			return fmt.Sprintf("%s IN (%s)", col, strings.Join(ps, ", "))
+		case filter.Contains:
+			return fmt.Sprintf(`%s ILIKE %s ESCAPE '\'`, col, c.param("%"+likeEscaper.Replace(e.Values[0])+"%"))
+		default:
+			return fmt.Sprintf("%s %s %s", collated(col), e.Op, c.param(e.Values[0]))
+		}
+	}
+	return ""
+}
+
+func (c *sqlCompiler) join(es []filter.Expr, sep string) string {
+	parts := make([]string, 0, len(es))
+	for _, e := range es {
+		if s := c.compile(e); s != "" {
+			parts = append(parts, s)
+		}
+	}
+	switch len(parts) {
+	case 0:
+		return ""
+	case 1:
+		return parts[0]
+	}
+	return "(" + strings.Join(parts, sep) + ")"
+}
@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"reflect"
+	"testing"
+
+	"practice5/filter"
+	"practice5/models"
+)
+
+func TestCompileFilter(t *testing.T) {
+	e, err := filter.Parse("gender IN (female, male) AND (age >= 25 OR name ~ 'a_5%') AND NOT name < B")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, args := compileFilter(e, 2)
+	want := `(gender IN ($2, $3) AND (date_part('year', age(birthdate)) >= $4 OR name ILIKE $5 ESCAPE '\') AND NOT (name COLLATE "C" < $6))`
+	if got != want {
+		t.Errorf("sql = %q\nwant  %q", got, want)
+	}
+	if wantArgs := []interface{}{"female", "male", "25", `%a\_5\%%`, "B"}; !reflect.DeepEqual(args, wantArgs) {
+		t.Errorf("args = %v; want %v", args, wantArgs)
+	}
+}
+
+func TestBuildFiltersFromParams(t *testing.T) {
+	from, minAge := "1990-01-01", 30
+	p := models.FilterParams{IDs: []int{1, 2}, BirthdateFrom: &from, AgeMin: &minAge}
+
+	clauses, args := buildFilters(p)
+	want := []string{"(id IN ($1, $2) AND birthdate >= $3 AND date_part('year', age(birthdate)) >= $4)", "deleted_at IS NULL"}
+	if !reflect.DeepEqual(clauses, want) {
+		t.Errorf("clauses = %q; want %q", clauses, want)
+	}
+	if len(args) != 4 {
+		t.Errorf("args = %v; want 4", args)
+	}
+
+	clauses, args = buildFilters(models.FilterParams{IncludeDeleted: true})
+	if len(clauses) != 0 || len(args) != 0 {
+		t.Errorf("empty params produced %q %v", clauses, args)
+	}
+}
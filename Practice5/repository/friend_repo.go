@@ -0,0 +1,228 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+
+	"practice5/models"
+)
+
+var (
+	ErrRequestNotFound = &Error{Kind: KindNotFound, Code: "friend_request_not_found", Message: "friend request not found"}
+	ErrRequestPending  = &Error{Kind: KindConflict, Code: "friend_request_pending", Message: "a pending friend request already exists between these users"}
+	ErrAlreadyFriends  = &Error{Kind: KindConflict, Code: "already_friends", Message: "users are already friends"}
+	ErrNotFriends      = &Error{Kind: KindNotFound, Code: "not_friends", Message: "users are not friends"}
+)
+
+const foreignKeyViolation = "23503"
+
+// GetFriends lists the friends of userID with the same filters, ordering and
+// pagination modes as GetPaginatedUsers.
+func (r *Repository) GetFriends(ctx context.Context, userID int, p models.FilterParams) (models.PaginatedResponse, error) {
+	if _, err := r.GetUserByID(ctx, userID); err != nil {
+		return models.PaginatedResponse{}, err
+	}
+	p.FriendsOf = &userID
+	return r.GetPaginatedUsers(ctx, p)
+}
+
+// GetFriendRequests returns the pending requests sent to userID (incoming)
+// or sent by userID (outgoing), newest first. Requests involving a
+// soft-deleted user are left out.
+func (r *Repository) GetFriendRequests(ctx context.Context, userID int, outgoing bool) (_ []models.FriendRequest, err error) {
+	defer classify(ctx, &err)
+	col := "addressee_id"
+	if outgoing {
+		col = "requester_id"
+	}
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT fr.requester_id, fr.addressee_id, fr.status, fr.created_at, fr.updated_at
+		FROM friend_requests fr
+		JOIN users req ON req.id = fr.requester_id AND req.deleted_at IS NULL
+		JOIN users adr ON adr.id = fr.addressee_id AND adr.deleted_at IS NULL
+		WHERE fr.`+col+` = $1 AND fr.status = 'pending'
+		ORDER BY fr.created_at DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	requests := []models.FriendRequest{}
+	for rows.Next() {
+		var fr models.FriendRequest
+		if err := rows.Scan(&fr.RequesterID, &fr.AddresseeID, &fr.Status, &fr.CreatedAt, &fr.UpdatedAt); err != nil {
+			return nil, err
+		}
+		requests = append(requests, fr)
+	}
+	return requests, rows.Err()
+}
+
+// SendFriendRequest creates a pending request from -> to. A previously
+// declined, cancelled or accepted-then-unfriended request is reopened. The
+// checks below give the usual answers; a unique index on the pair settles
+// races between them and the insert.
+func (r *Repository) SendFriendRequest(ctx context.Context, from, to int) (_ models.FriendRequest, err error) {
+	defer classify(ctx, &err)
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.FriendRequest{}, err
+	}
+	defer tx.Rollback()
+
+	// The foreign keys only catch users that are gone for good.
+	if ok, err := bothActive(ctx, tx, from, to); err != nil || !ok {
+		if err == nil {
+			err = ErrNotFound
+		}
+		return models.FriendRequest{}, err
+	}
+
+	var exists bool
+	if err := tx.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM user_friends WHERE user_id = $1 AND friend_id = $2)`, from, to,
+	).Scan(&exists); err != nil {
+		return models.FriendRequest{}, err
+	}
+	if exists {
+		return models.FriendRequest{}, ErrAlreadyFriends
+	}
+
+	if err := tx.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM friend_requests
+			WHERE requester_id = $1 AND addressee_id = $2 AND status = 'pending'
+		)`, to, from,
+	).Scan(&exists); err != nil {
+		return models.FriendRequest{}, err
+	}
+	if exists {
+		return models.FriendRequest{}, ErrRequestPending
+	}
+
+	var fr models.FriendRequest
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO friend_requests (requester_id, addressee_id)
+		VALUES ($1, $2)
+		ON CONFLICT (requester_id, addressee_id) DO UPDATE
+			SET status = 'pending', created_at = now(), updated_at = now()
+			WHERE friend_requests.status <> 'pending'
+		RETURNING requester_id, addressee_id, status, created_at, updated_at`,
+		from, to,
+	).Scan(&fr.RequesterID, &fr.AddresseeID, &fr.Status, &fr.CreatedAt, &fr.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fr, ErrRequestPending
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case foreignKeyViolation:
+			return fr, ErrNotFound
+		case uniqueViolation:
+			// A concurrent request the other way won the race; see
+			// migration 0006.
+			return fr, ErrRequestPending
+		}
+	}
+	if err != nil {
+		return fr, err
+	}
+	return fr, tx.Commit()
+}
+
+// AcceptFriendRequest marks the pending request from -> to as accepted and
+// writes both directed user_friends rows in the same transaction. A request
+// involving a soft-deleted user is not listed, so it cannot be accepted.
+func (r *Repository) AcceptFriendRequest(ctx context.Context, from, to int) (err error) {
+	defer classify(ctx, &err)
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if ok, err := bothActive(ctx, tx, from, to); err != nil || !ok {
+		if err == nil {
+			err = ErrRequestNotFound
+		}
+		return err
+	}
+	if err := resolveRequest(ctx, tx, from, to, "accepted"); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO user_friends (user_id, friend_id)
+		VALUES ($1, $2), ($2, $1)
+		ON CONFLICT DO NOTHING`, from, to,
+	); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeclineFriendRequest is called by the addressee of the request.
+func (r *Repository) DeclineFriendRequest(ctx context.Context, from, to int) (err error) {
+	defer classify(ctx, &err)
+	return resolveRequest(ctx, r.db, from, to, "declined")
+}
+
+// CancelFriendRequest is called by the requester.
+func (r *Repository) CancelFriendRequest(ctx context.Context, from, to int) (err error) {
+	defer classify(ctx, &err)
+	return resolveRequest(ctx, r.db, from, to, "cancelled")
+}
+
+// Unfriend removes both directed rows of the friendship.
+func (r *Repository) Unfriend(ctx context.Context, userID, friendID int) (err error) {
+	defer classify(ctx, &err)
+	res, err := r.db.ExecContext(ctx, `
+		DELETE FROM user_friends
+		WHERE (user_id = $1 AND friend_id = $2)
+		   OR (user_id = $2 AND friend_id = $1)`, userID, friendID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFriends
+	}
+	return nil
+}
+
+// bothActive reports whether users a and b exist and are not soft-deleted.
+func bothActive(ctx context.Context, tx *sql.Tx, a, b int) (bool, error) {
+	var active int
+	err := tx.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM users WHERE id IN ($1, $2) AND deleted_at IS NULL`, a, b,
+	).Scan(&active)
+	return active == 2, err
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// resolveRequest moves a pending request to its final status.
+func resolveRequest(ctx context.Context, db execer, from, to int, status string) error {
+	res, err := db.ExecContext(ctx, `
+		UPDATE friend_requests SET status = $3, updated_at = now()
+		WHERE requester_id = $1 AND addressee_id = $2 AND status = 'pending'`,
+		from, to, status)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrRequestNotFound
+	}
+	return nil
+}
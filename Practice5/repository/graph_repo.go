@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"practice5/models"
+)
+
+// FriendGraph loads every user id and friendship in one repeatable-read
+// transaction, so edges never point at users missing from the snapshot.
+// Soft-deleted users and their friendships are left out.
+func (r *Repository) FriendGraph(ctx context.Context) (g models.FriendGraph, err error) {
+	defer classify(ctx, &err)
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return g, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `SELECT id FROM users WHERE deleted_at IS NULL ORDER BY id`)
+	if err != nil {
+		return g, err
+	}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return g, err
+		}
+		g.UserIDs = append(g.UserIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return g, err
+	}
+
+	// Both directions are stored; one is enough here.
+	rows, err = tx.QueryContext(ctx, `
+		SELECT uf.user_id, uf.friend_id FROM user_friends uf
+		JOIN users a ON a.id = uf.user_id AND a.deleted_at IS NULL
+		JOIN users b ON b.id = uf.friend_id AND b.deleted_at IS NULL
+		WHERE uf.user_id < uf.friend_id ORDER BY 1, 2`)
+	if err != nil {
+		return g, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var e [2]int
+		if err := rows.Scan(&e[0], &e[1]); err != nil {
+			return g, err
+		}
+		g.Edges = append(g.Edges, e)
+	}
+	return g, rows.Err()
+}
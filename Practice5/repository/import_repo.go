@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"practice5/models"
+)
+
+const importBatchSize = 500
+
+// ImportUsers inserts rows inside one transaction with batched multi-row
+// INSERTs. Rows whose email already exists, in the table or earlier in the
+// input, are reported as failures. With allOrNothing any failure rolls the
+// whole import back; otherwise the remaining rows are committed.
+// It returns the number of rows inserted and the failures.
+func (r *Repository) ImportUsers(ctx context.Context, rows []models.ImportRow, allOrNothing bool) (_ int, _ []models.ImportError, err error) {
+	defer classify(ctx, &err)
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer tx.Rollback()
+
+	emails := make([]string, len(rows))
+	for i, row := range rows {
+		emails[i] = *row.Input.Email
+	}
+	existing := map[string]bool{}
+	dbRows, err := tx.QueryContext(ctx, `SELECT email FROM users WHERE email = ANY($1)`, pq.Array(emails))
+	if err != nil {
+		return 0, nil, err
+	}
+	for dbRows.Next() {
+		var e string
+		if err := dbRows.Scan(&e); err != nil {
+			dbRows.Close()
+			return 0, nil, err
+		}
+		existing[e] = true
+	}
+	dbRows.Close()
+	if err := dbRows.Err(); err != nil {
+		return 0, nil, err
+	}
+
+	var failures []models.ImportError
+	firstLine := map[string]int{}
+	var pending []models.ImportRow
+	for _, row := range rows {
+		email := *row.Input.Email
+		switch {
+		case existing[email]:
+			failures = append(failures, models.ImportError{Line: row.Line, Email: email, Message: ErrEmailTaken.Error()})
+		case firstLine[email] != 0:
+			failures = append(failures, models.ImportError{
+				Line: row.Line, Email: email, Message: fmt.Sprintf("duplicate of line %d", firstLine[email]),
+			})
+		default:
+			firstLine[email] = row.Line
+			pending = append(pending, row)
+		}
+	}
+	if allOrNothing && len(failures) > 0 {
+		return 0, failures, nil
+	}
+
+	imported := 0
+	for start := 0; start < len(pending); start += importBatchSize {
+		batch := pending[start:min(start+importBatchSize, len(pending))]
+		inserted, err := insertBatch(ctx, tx, batch)
+		if err != nil {
+			return 0, nil, err
+		}
+		// Rows skipped by ON CONFLICT were inserted concurrently by someone else.
+		for _, row := range batch {
+			if !inserted[*row.Input.Email] {
+				failures = append(failures, models.ImportError{
+					Line: row.Line, Email: *row.Input.Email, Message: ErrEmailTaken.Error(),
+				})
+			}
+		}
+		imported += len(inserted)
+	}
+	if allOrNothing && len(failures) > 0 {
+		return 0, failures, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, nil, err
+	}
+	return imported, failures, nil
+}
+
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+func insertBatch(ctx context.Context, tx queryer, batch []models.ImportRow) (map[string]bool, error) {
+	values := make([]string, len(batch))
+	args := make([]interface{}, 0, len(batch)*4)
+	for i, row := range batch {
+		n := i * 4
+		values[i] = fmt.Sprintf("($%d, $%d, $%d, $%d)", n+1, n+2, n+3, n+4)
+		args = append(args, *row.Input.Name, *row.Input.Email, *row.Input.Gender, *row.Input.Birthdate)
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		INSERT INTO users (name, email, gender, birthdate)
+		VALUES `+strings.Join(values, ", ")+`
+		ON CONFLICT (email) DO NOTHING
+		RETURNING email`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	inserted := map[string]bool{}
+	for rows.Next() {
+		var e string
+		if err := rows.Scan(&e); err != nil {
+			return nil, err
+		}
+		inserted[e] = true
+	}
+	return inserted, rows.Err()
+}
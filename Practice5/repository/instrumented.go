@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"practice5/models"
+)
+
+// Observer receives the duration and outcome of every store call.
+type Observer func(method string, d time.Duration, err error)
+
+// Instrument wraps s so every call is reported to observe. ExportUsers is
+// timed including fn, i.e. the time spent writing rows to the client.
+func Instrument(s UserStore, observe Observer) UserStore {
+	return &instrumentedStore{next: s, observe: observe}
+}
+
+type instrumentedStore struct {
+	next    UserStore
+	observe Observer
+}
+
+func (s *instrumentedStore) track(method string, start time.Time, err *error) {
+	s.observe(method, time.Since(start), *err)
+}
+
+func (s *instrumentedStore) GetPaginatedUsers(ctx context.Context, p models.FilterParams) (_ models.PaginatedResponse, err error) {
+	defer s.track("GetPaginatedUsers", time.Now(), &err)
+	return s.next.GetPaginatedUsers(ctx, p)
+}
+
+func (s *instrumentedStore) GetCommonFriends(ctx context.Context, userID1, userID2 int, includeDeleted bool) (_ []models.User, err error) {
+	defer s.track("GetCommonFriends", time.Now(), &err)
+	return s.next.GetCommonFriends(ctx, userID1, userID2, includeDeleted)
+}
+
+func (s *instrumentedStore) GetUserByID(ctx context.Context, id int) (_ models.User, err error) {
+	defer s.track("GetUserByID", time.Now(), &err)
+	return s.next.GetUserByID(ctx, id)
+}
+
+func (s *instrumentedStore) CreateUser(ctx context.Context, in models.UserInput) (_ models.User, err error) {
+	defer s.track("CreateUser", time.Now(), &err)
+	return s.next.CreateUser(ctx, in)
+}
+
+func (s *instrumentedStore) UpdateUser(ctx context.Context, id int, in models.UserInput) (_ models.User, err error) {
+	defer s.track("UpdateUser", time.Now(), &err)
+	return s.next.UpdateUser(ctx, id, in)
+}
+
+func (s *instrumentedStore) DeleteUser(ctx context.Context, id int) (err error) {
+	defer s.track("DeleteUser", time.Now(), &err)
+	return s.next.DeleteUser(ctx, id)
+}
+
+func (s *instrumentedStore) RestoreUser(ctx context.Context, id int) (_ models.User, err error) {
+	defer s.track("RestoreUser", time.Now(), &err)
+	return s.next.RestoreUser(ctx, id)
+}
+
+func (s *instrumentedStore) PurgeDeletedUsers(ctx context.Context, cutoff time.Time) (_ []int, err error) {
+	defer s.track("PurgeDeletedUsers", time.Now(), &err)
+	return s.next.PurgeDeletedUsers(ctx, cutoff)
+}
+
+func (s *instrumentedStore) GetFriends(ctx context.Context, userID int, p models.FilterParams) (_ models.PaginatedResponse, err error) {
+	defer s.track("GetFriends", time.Now(), &err)
+	return s.next.GetFriends(ctx, userID, p)
+}
+
+func (s *instrumentedStore) FriendLists(ctx context.Context, userIDs []int) (_ map[int][]models.User, err error) {
+	defer s.track("FriendLists", time.Now(), &err)
+	return s.next.FriendLists(ctx, userIDs)
+}
+
+func (s *instrumentedStore) GetFriendRequests(ctx context.Context, userID int, outgoing bool) (_ []models.FriendRequest, err error) {
+	defer s.track("GetFriendRequests", time.Now(), &err)
+	return s.next.GetFriendRequests(ctx, userID, outgoing)
+}
+
+func (s *instrumentedStore) SendFriendRequest(ctx context.Context, from, to int) (_ models.FriendRequest, err error) {
+	defer s.track("SendFriendRequest", time.Now(), &err)
+	return s.next.SendFriendRequest(ctx, from, to)
+}
+
+func (s *instrumentedStore) AcceptFriendRequest(ctx context.Context, from, to int) (err error) {
+	defer s.track("AcceptFriendRequest", time.Now(), &err)
+	return s.next.AcceptFriendRequest(ctx, from, to)
+}
+
+func (s *instrumentedStore) DeclineFriendRequest(ctx context.Context, from, to int) (err error) {
+	defer s.track("DeclineFriendRequest", time.Now(), &err)
+	return s.next.DeclineFriendRequest(ctx, from, to)
+}
+
+func (s *instrumentedStore) CancelFriendRequest(ctx context.Context, from, to int) (err error) {
+	defer s.track("CancelFriendRequest", time.Now(), &err)
+	return s.next.CancelFriendRequest(ctx, from, to)
+}
+
+func (s *instrumentedStore) Unfriend(ctx context.Context, userID, friendID int) (err error) {
+	defer s.track("Unfriend", time.Now(), &err)
+	return s.next.Unfriend(ctx, userID, friendID)
+}
+
+func (s *instrumentedStore) GetFriendSuggestions(ctx context.Context, userID, limit, sampleSize int) (_ []models.FriendSuggestion, err error) {
+	defer s.track("GetFriendSuggestions", time.Now(), &err)
+	return s.next.GetFriendSuggestions(ctx, userID, limit, sampleSize)
+}
+
+func (s *instrumentedStore) FindFriendPath(ctx context.Context, from, to, maxDepth int) (_ models.FriendPath, err error) {
+	defer s.track("FindFriendPath", time.Now(), &err)
+	return s.next.FindFriendPath(ctx, from, to, maxDepth)
+}
+
+func (s *instrumentedStore) FriendGraph(ctx context.Context) (_ models.FriendGraph, err error) {
+	defer s.track("FriendGraph", time.Now(), &err)
+	return s.next.FriendGraph(ctx)
+}
+
+func (s *instrumentedStore) ExportUsers(ctx context.Context, p models.FilterParams, fn func(batch []models.User) error) (err error) {
+	defer s.track("ExportUsers", time.Now(), &err)
+	return s.next.ExportUsers(ctx, p, fn)
+}
+
+func (s *instrumentedStore) ImportUsers(ctx context.Context, rows []models.ImportRow, allOrNothing bool) (_ int, _ []models.ImportError, err error) {
+	defer s.track("ImportUsers", time.Now(), &err)
+	return s.next.ImportUsers(ctx, rows, allOrNothing)
+}
+
+func (s *instrumentedStore) DataVersion(ctx context.Context) (_ int64, err error) {
+	defer s.track("DataVersion", time.Now(), &err)
+	return s.next.DataVersion(ctx)
+}
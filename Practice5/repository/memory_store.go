@@ -0,0 +1,662 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"practice5/filter"
+	"practice5/models"
+)
+
+// MemoryStore is an in-process UserStore with the same filtering, ordering,
+// pagination and error semantics as Repository. It backs --store=memory
+// and the conformance tests.
+type MemoryStore struct {
+	mu       sync.RWMutex
+	users    map[int]models.User
+	nextID   int
+	friends  map[int]map[int]bool // directed, like user_friends
+	requests map[[2]int]*models.FriendRequest
+	version  int64 // bumped by every change to users or friends
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		users:    map[int]models.User{},
+		nextID:   1,
+		friends:  map[int]map[int]bool{},
+		requests: map[[2]int]*models.FriendRequest{},
+	}
+}
+
+var (
+	seedUserRow   = regexp.MustCompile(`\(\s*'([^']*)',\s*'([^']*)',\s*'([^']*)',\s*'([^']*)'\s*\)`)
+	seedFriendRow = regexp.MustCompile(`\(\s*(\d+)\s*,\s*(\d+)\s*\)`)
+)
+
+// LoadSeed reads the INSERT statements of migrations/seed.sql so that the
+// memory backend starts with the same demo data as Postgres.
+func (m *MemoryStore) LoadSeed(seedSQL string) error {
+	usersPart, friendsPart, ok := strings.Cut(seedSQL, "INSERT INTO user_friends")
+	if !ok {
+		return fmt.Errorf("seed has no user_friends INSERT")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, row := range seedUserRow.FindAllStringSubmatch(usersPart, -1) {
+		birthdate, err := time.Parse("2006-01-02", row[4])
+		if err != nil {
+			return fmt.Errorf("seed user %q: %w", row[1], err)
+		}
+		u := models.User{ID: m.nextID, Name: row[1], Email: row[2], Gender: row[3], Birthdate: birthdate}
+		m.users[u.ID] = u
+		m.nextID++
+	}
+	for _, row := range seedFriendRow.FindAllStringSubmatch(friendsPart, -1) {
+		a, _ := strconv.Atoi(row[1])
+		b, _ := strconv.Atoi(row[2])
+		if _, ok := m.users[a]; !ok {
+			return fmt.Errorf("seed friendship references unknown user %d", a)
+		}
+		if _, ok := m.users[b]; !ok {
+			return fmt.Errorf("seed friendship references unknown user %d", b)
+		}
+		m.addFriend(a, b)
+	}
+	m.version++
+	return nil
+}
+
+func (m *MemoryStore) addFriend(a, b int) {
+	if m.friends[a] == nil {
+		m.friends[a] = map[int]bool{}
+	}
+	m.friends[a][b] = true
+}
+
+// ─── Users ────────────────────────────────────────────────────────────────────
+
+func (m *MemoryStore) GetPaginatedUsers(ctx context.Context, p models.FilterParams) (models.PaginatedResponse, error) {
+	keys, err := checkParams(p)
+	if err != nil {
+		return models.PaginatedResponse{}, err
+	}
+
+	m.mu.RLock()
+	users := m.matching(p)
+	m.mu.RUnlock()
+
+	if p.Cursor != nil {
+		var c cursor
+		if *p.Cursor != "" {
+			if c, err = decodeCursor(*p.Cursor, keys); err != nil {
+				return models.PaginatedResponse{}, err
+			}
+		}
+		sortUsers(users, keys, c.Backward)
+		var page []models.User
+		for _, u := range users {
+			if len(page) > p.PageSize {
+				break
+			}
+			if *p.Cursor == "" || afterCursor(u, keys, c) {
+				page = append(page, u)
+			}
+		}
+		resp := cursorPage(page, keys, c, *p.Cursor != "", p.PageSize)
+		m.loadRelations(resp.Data, p.Include)
+		return resp, nil
+	}
+
+	sortUsers(users, keys, false)
+	var data []models.User
+	if offset, ok := pageOffset(p.Page, p.PageSize, len(users)); ok && offset < len(users) {
+		data = users[offset:]
+		data = data[:min(p.PageSize, len(data))]
+	}
+	resp := offsetPage(data, len(users), p.Page, p.PageSize)
+	m.loadRelations(resp.Data, p.Include)
+	return resp, nil
+}
+
+func (m *MemoryStore) loadRelations(users []models.User, inc models.Include) {
+	if !inc.FriendCount && inc.Friends == 0 {
+		return
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for i := range users {
+		ids := m.activeFriends(users[i].ID)
+		var friends []models.User
+		for _, id := range ids[:min(inc.Friends, len(ids))] {
+			friends = append(friends, m.users[id])
+		}
+		attachRelations(&users[i], inc, len(ids), friends)
+	}
+}
+
+func (m *MemoryStore) FriendLists(ctx context.Context, userIDs []int) (map[int][]models.User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	lists := make(map[int][]models.User, len(userIDs))
+	for _, id := range userIDs {
+		for _, f := range m.activeFriends(id) {
+			lists[id] = append(lists[id], m.users[f])
+		}
+	}
+	return lists, nil
+}
+
+// active reports whether id exists and is not soft-deleted.
+func (m *MemoryStore) active(id int) bool {
+	u, ok := m.users[id]
+	return ok && u.DeletedAt == nil
+}
+
+// activeFriends lists the friends of id that are not soft-deleted, lowest
+// id first, the in-memory twin of joining users with deleted_at IS NULL.
+func (m *MemoryStore) activeFriends(id int) []int {
+	var ids []int
+	for _, f := range sortedIDs(m.friends[id]) {
+		if m.active(f) {
+			ids = append(ids, f)
+		}
+	}
+	return ids
+}
+
+func (m *MemoryStore) GetCommonFriends(ctx context.Context, userID1, userID2 int, includeDeleted bool) ([]models.User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var users []models.User
+	for _, id := range sortedIDs(m.friends[userID1]) {
+		if m.friends[userID2][id] && (includeDeleted || m.users[id].DeletedAt == nil) {
+			users = append(users, m.users[id])
+		}
+	}
+	return users, nil
+}
+
+func (m *MemoryStore) GetUserByID(ctx context.Context, id int) (models.User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	u, ok := m.users[id]
+	if !ok || u.DeletedAt != nil {
+		return models.User{}, ErrNotFound
+	}
+	return u, nil
+}
+
+func (m *MemoryStore) CreateUser(ctx context.Context, in models.UserInput) (models.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	u := models.User{ID: m.nextID}
+	if err := m.apply(&u, in); err != nil {
+		return models.User{}, err
+	}
+	m.users[u.ID] = u
+	m.nextID++
+	m.version++
+	return u, nil
+}
+
+func (m *MemoryStore) UpdateUser(ctx context.Context, id int, in models.UserInput) (models.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	u, ok := m.users[id]
+	if !ok || u.DeletedAt != nil {
+		return models.User{}, ErrNotFound
+	}
+	if err := m.apply(&u, in); err != nil {
+		return models.User{}, err
+	}
+	m.users[id] = u
+	m.version++
+	return u, nil
+}
+
+func (m *MemoryStore) DeleteUser(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	u, ok := m.users[id]
+	if !ok || u.DeletedAt != nil {
+		return ErrNotFound
+	}
+	now := time.Now()
+	u.DeletedAt = &now
+	m.users[id] = u
+	m.version++
+	return nil
+}
+
+func (m *MemoryStore) RestoreUser(ctx context.Context, id int) (models.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	u, ok := m.users[id]
+	if !ok {
+		return models.User{}, ErrNotFound
+	}
+	if u.DeletedAt != nil {
+		u.DeletedAt = nil
+		m.users[id] = u
+		m.version++
+	}
+	return u, nil
+}
+
+func (m *MemoryStore) PurgeDeletedUsers(ctx context.Context, cutoff time.Time) ([]int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var ids []int
+	for id, u := range m.users {
+		if u.DeletedAt != nil && u.DeletedAt.Before(cutoff) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		delete(m.users, id)
+		delete(m.friends, id)
+		for _, fs := range m.friends {
+			delete(fs, id)
+		}
+		for k := range m.requests {
+			if k[0] == id || k[1] == id {
+				delete(m.requests, k)
+			}
+		}
+	}
+	if len(ids) > 0 {
+		m.version++
+	}
+	return ids, nil
+}
+
+// apply copies the non-nil fields of in onto u, enforcing unique emails.
+func (m *MemoryStore) apply(u *models.User, in models.UserInput) error {
+	if in.Email != nil && m.emailTaken(*in.Email, u.ID) {
+		return ErrEmailTaken
+	}
+	if in.Birthdate != nil {
+		d, err := time.Parse("2006-01-02", *in.Birthdate)
+		if err != nil {
+			return err
+		}
+		u.Birthdate = d
+	}
+	if in.Name != nil {
+		u.Name = *in.Name
+	}
+	if in.Email != nil {
+		u.Email = *in.Email
+	}
+	if in.Gender != nil {
+		u.Gender = *in.Gender
+	}
+	return nil
+}
+
+func (m *MemoryStore) emailTaken(email string, exceptID int) bool {
+	for _, u := range m.users {
+		if u.Email == email && u.ID != exceptID {
+			return true
+		}
+	}
+	return false
+}
+
+// matching returns a copy of every user passing p's filters. Callers hold mu.
+func (m *MemoryStore) matching(p models.FilterParams) []models.User {
+	expr := p.Expr()
+	var users []models.User
+	for _, u := range m.users {
+		if p.FriendsOf != nil && !m.friends[*p.FriendsOf][u.ID] {
+			continue
+		}
+		if u.DeletedAt != nil && !p.IncludeDeleted {
+			continue
+		}
+		if matchFilter(expr, u) {
+			users = append(users, u)
+		}
+	}
+	return users
+}
+
+// ─── Friendships ──────────────────────────────────────────────────────────────
+
+func (m *MemoryStore) GetFriends(ctx context.Context, userID int, p models.FilterParams) (models.PaginatedResponse, error) {
+	if _, err := m.GetUserByID(ctx, userID); err != nil {
+		return models.PaginatedResponse{}, err
+	}
+	p.FriendsOf = &userID
+	return m.GetPaginatedUsers(ctx, p)
+}
+
+func (m *MemoryStore) GetFriendRequests(ctx context.Context, userID int, outgoing bool) ([]models.FriendRequest, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	requests := []models.FriendRequest{}
+	for k, fr := range m.requests {
+		side := k[1]
+		if outgoing {
+			side = k[0]
+		}
+		if side == userID && fr.Status == "pending" && m.active(k[0]) && m.active(k[1]) {
+			requests = append(requests, *fr)
+		}
+	}
+	sort.Slice(requests, func(i, j int) bool { return requests[i].CreatedAt.After(requests[j].CreatedAt) })
+	return requests, nil
+}
+
+func (m *MemoryStore) SendFriendRequest(ctx context.Context, from, to int) (models.FriendRequest, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.active(from) || !m.active(to) {
+		return models.FriendRequest{}, ErrNotFound
+	}
+	if m.friends[from][to] {
+		return models.FriendRequest{}, ErrAlreadyFriends
+	}
+	if fr := m.requests[[2]int{to, from}]; fr != nil && fr.Status == "pending" {
+		return models.FriendRequest{}, ErrRequestPending
+	}
+	if fr := m.requests[[2]int{from, to}]; fr != nil && fr.Status == "pending" {
+		return models.FriendRequest{}, ErrRequestPending
+	}
+
+	now := time.Now()
+	fr := &models.FriendRequest{RequesterID: from, AddresseeID: to, Status: "pending", CreatedAt: now, UpdatedAt: now}
+	m.requests[[2]int{from, to}] = fr
+	return *fr, nil
+}
+
+func (m *MemoryStore) AcceptFriendRequest(ctx context.Context, from, to int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.active(from) || !m.active(to) {
+		return ErrRequestNotFound
+	}
+	if err := m.resolve(from, to, "accepted"); err != nil {
+		return err
+	}
+	m.addFriend(from, to)
+	m.addFriend(to, from)
+	m.version++
+	return nil
+}
+
+func (m *MemoryStore) DeclineFriendRequest(ctx context.Context, from, to int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.resolve(from, to, "declined")
+}
+
+func (m *MemoryStore) CancelFriendRequest(ctx context.Context, from, to int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.resolve(from, to, "cancelled")
+}
+
+func (m *MemoryStore) resolve(from, to int, status string) error {
+	fr := m.requests[[2]int{from, to}]
+	if fr == nil || fr.Status != "pending" {
+		return ErrRequestNotFound
+	}
+	fr.Status = status
+	fr.UpdatedAt = time.Now()
+	return nil
+}
+
+func (m *MemoryStore) Unfriend(ctx context.Context, userID, friendID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.friends[userID][friendID] && !m.friends[friendID][userID] {
+		return ErrNotFriends
+	}
+	delete(m.friends[userID], friendID)
+	delete(m.friends[friendID], userID)
+	m.version++
+	return nil
+}
+
+func (m *MemoryStore) GetFriendSuggestions(ctx context.Context, userID, limit, sampleSize int) ([]models.FriendSuggestion, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if !m.active(userID) {
+		return nil, ErrNotFound
+	}
+
+	byID := map[int]*models.FriendSuggestion{}
+	for _, friend := range m.activeFriends(userID) {
+		for _, candidate := range m.activeFriends(friend) {
+			if candidate == userID || m.friends[userID][candidate] {
+				continue
+			}
+			s := byID[candidate]
+			if s == nil {
+				s = &models.FriendSuggestion{User: m.users[candidate]}
+				byID[candidate] = s
+			}
+			s.MutualCount++
+			s.MutualFriends = append(s.MutualFriends, m.users[friend].Name)
+		}
+	}
+
+	suggestions := []models.FriendSuggestion{}
+	for _, s := range byID {
+		sort.Strings(s.MutualFriends)
+		s.MutualFriends = s.MutualFriends[:min(sampleSize, len(s.MutualFriends))]
+		suggestions = append(suggestions, *s)
+	}
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].MutualCount != suggestions[j].MutualCount {
+			return suggestions[i].MutualCount > suggestions[j].MutualCount
+		}
+		return suggestions[i].ID < suggestions[j].ID
+	})
+	return suggestions[:min(limit, len(suggestions))], nil
+}
+
+func (m *MemoryStore) FindFriendPath(ctx context.Context, from, to, maxDepth int) (models.FriendPath, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, id := range []int{from, to} {
+		if !m.active(id) {
+			return models.FriendPath{}, ErrNotFound
+		}
+	}
+
+	ids, err := shortestPath(from, to, maxDepth, func(frontier []int, forward bool) (map[int][]int, error) {
+		edges := map[int][]int{}
+		for _, n := range frontier {
+			if forward {
+				edges[n] = m.activeFriends(n)
+				continue
+			}
+			for other, fs := range m.friends {
+				if fs[n] && m.active(other) {
+					edges[n] = append(edges[n], other)
+				}
+			}
+			sort.Ints(edges[n])
+		}
+		return edges, nil
+	})
+	if err != nil {
+		return models.FriendPath{}, err
+	}
+
+	path := models.FriendPath{Hops: len(ids) - 1}
+	for _, id := range ids {
+		path.Users = append(path.Users, m.users[id])
+	}
+	return path, nil
+}
+
+func (m *MemoryStore) FriendGraph(ctx context.Context) (models.FriendGraph, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	g := models.FriendGraph{UserIDs: make([]int, 0, len(m.users))}
+	for id := range m.users {
+		if m.active(id) {
+			g.UserIDs = append(g.UserIDs, id)
+		}
+	}
+	sort.Ints(g.UserIDs)
+	for _, id := range g.UserIDs {
+		for _, f := range m.activeFriends(id) {
+			if id < f {
+				g.Edges = append(g.Edges, [2]int{id, f})
+			}
+		}
+	}
+	return g, nil
+}
+
+// ─── Export / import ──────────────────────────────────────────────────────────
+
+func (m *MemoryStore) ExportUsers(ctx context.Context, p models.FilterParams, fn func(batch []models.User) error) (err error) {
+	defer classify(ctx, &err)
+	keys, err := checkParams(p)
+	if err != nil {
+		return err
+	}
+
+	m.mu.RLock()
+	users := m.matching(p)
+	m.mu.RUnlock()
+
+	sortUsers(users, keys, false)
+	for start := 0; start < len(users); start += exportBatchSize {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(users[start:min(start+exportBatchSize, len(users))]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) ImportUsers(ctx context.Context, rows []models.ImportRow, allOrNothing bool) (int, []models.ImportError, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var failures []models.ImportError
+	firstLine := map[string]int{}
+	var pending []models.ImportRow
+	for _, row := range rows {
+		email := *row.Input.Email
+		switch {
+		case m.emailTaken(email, 0):
+			failures = append(failures, models.ImportError{Line: row.Line, Email: email, Message: ErrEmailTaken.Error()})
+		case firstLine[email] != 0:
+			failures = append(failures, models.ImportError{
+				Line: row.Line, Email: email, Message: fmt.Sprintf("duplicate of line %d", firstLine[email]),
+			})
+		default:
+			firstLine[email] = row.Line
+			pending = append(pending, row)
+		}
+	}
+	if allOrNothing && len(failures) > 0 {
+		return 0, failures, nil
+	}
+
+	for _, row := range pending {
+		u := models.User{ID: m.nextID}
+		if err := m.apply(&u, row.Input); err != nil {
+			return 0, nil, err
+		}
+		m.users[u.ID] = u
+		m.nextID++
+	}
+	if len(pending) > 0 {
+		m.version++
+	}
+	return len(pending), failures, nil
+}
+
+func (m *MemoryStore) DataVersion(ctx context.Context) (int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.version, nil
+}
+
+// ─── Ordering helpers ─────────────────────────────────────────────────────────
+
+// compareUsers orders a and b by keys, honouring each key's direction.
+func compareUsers(a, b models.User, keys []sortKey) int {
+	for _, k := range keys {
+		if c := compareKey(k, columnValue(a, k.col), columnValue(b, k.col)); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+func compareKey(k sortKey, a, b string) int {
+	kind := filter.Text
+	if k.col == "id" {
+		kind = filter.Int
+	}
+	c := compareValues(kind, a, b)
+	if k.desc {
+		return -c
+	}
+	return c
+}
+
+func sortUsers(users []models.User, keys []sortKey, backward bool) {
+	sort.Slice(users, func(i, j int) bool {
+		c := compareUsers(users[i], users[j], keys)
+		if backward {
+			return c > 0
+		}
+		return c < 0
+	})
+}
+
+// afterCursor reports whether u lies strictly beyond c in c's direction,
+// the in-memory twin of keysetClause.
+func afterCursor(u models.User, keys []sortKey, c cursor) bool {
+	for i, k := range keys {
+		if cmp := compareKey(k, columnValue(u, k.col), c.Values[i]); cmp != 0 {
+			return (cmp > 0) != c.Backward
+		}
+	}
+	return false
+}
+
+func sortedIDs(set map[int]bool) []int {
+	ids := make([]int, 0, len(set))
+	for id := range set {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
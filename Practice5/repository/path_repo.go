@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lib/pq"
+
+	"practice5/models"
+)
+
+var ErrNoPath = &Error{Kind: KindNotFound, Code: "no_path", Message: "no friendship path within the maximum depth"}
+
+// expandFunc returns the neighbours of every node in frontier. forward
+// follows user_id -> friend_id, backward follows friend_id -> user_id.
+type expandFunc func(frontier []int, forward bool) (map[int][]int, error)
+
+// FindFriendPath returns the shortest chain of friends from -> to with at
+// most maxDepth hops. Each BFS level costs one query over user_friends.
+func (r *Repository) FindFriendPath(ctx context.Context, from, to, maxDepth int) (_ models.FriendPath, err error) {
+	defer classify(ctx, &err)
+	for _, id := range []int{from, to} {
+		if _, err := r.GetUserByID(ctx, id); err != nil {
+			return models.FriendPath{}, err
+		}
+	}
+
+	ids, err := shortestPath(from, to, maxDepth, func(frontier []int, forward bool) (map[int][]int, error) {
+		return r.friendEdges(ctx, frontier, forward)
+	})
+	if err != nil {
+		return models.FriendPath{}, err
+	}
+
+	users, err := r.queryUsers(ctx,
+		`SELECT id, name, email, gender, birthdate FROM users WHERE id = ANY($1) AND deleted_at IS NULL`, pq.Array(ids),
+	)
+	if err != nil {
+		return models.FriendPath{}, err
+	}
+	byID := make(map[int]models.User, len(users))
+	for _, u := range users {
+		byID[u.ID] = u
+	}
+	path := models.FriendPath{Users: make([]models.User, len(ids)), Hops: len(ids) - 1}
+	for i, id := range ids {
+		u, ok := byID[id]
+		if !ok {
+			return models.FriendPath{}, fmt.Errorf("user %d on path disappeared", id)
+		}
+		path.Users[i] = u
+	}
+	return path, nil
+}
+
+func (r *Repository) friendEdges(ctx context.Context, frontier []int, forward bool) (map[int][]int, error) {
+	// Soft-deleted users are not stepped through.
+	query := `
+		SELECT uf.user_id, uf.friend_id FROM user_friends uf
+		JOIN users u ON u.id = uf.friend_id AND u.deleted_at IS NULL
+		WHERE uf.user_id = ANY($1) ORDER BY 1, 2`
+	if !forward {
+		query = `
+		SELECT uf.friend_id, uf.user_id FROM user_friends uf
+		JOIN users u ON u.id = uf.user_id AND u.deleted_at IS NULL
+		WHERE uf.friend_id = ANY($1) ORDER BY 1, 2`
+	}
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(frontier))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	edges := make(map[int][]int)
+	for rows.Next() {
+		var node, next int
+		if err := rows.Scan(&node, &next); err != nil {
+			return nil, err
+		}
+		edges[node] = append(edges[node], next)
+	}
+	return edges, rows.Err()
+}
+
+// shortestPath runs a bidirectional BFS, always growing the smaller
+// frontier by one level. Once a level touches the other side's visited set,
+// the meeting node with the shortest total distance is picked.
+func shortestPath(from, to, maxDepth int, expand expandFunc) ([]int, error) {
+	if from == to {
+		return []int{from}, nil
+	}
+
+	type side struct {
+		parent   map[int]int
+		dist     map[int]int
+		frontier []int
+		depth    int
+	}
+	fwd := &side{parent: map[int]int{from: from}, dist: map[int]int{from: 0}, frontier: []int{from}}
+	bwd := &side{parent: map[int]int{to: to}, dist: map[int]int{to: 0}, frontier: []int{to}}
+
+	for fwd.depth+bwd.depth < maxDepth && len(fwd.frontier) > 0 && len(bwd.frontier) > 0 {
+		cur, other, forward := fwd, bwd, true
+		if len(bwd.frontier) < len(fwd.frontier) {
+			cur, other, forward = bwd, fwd, false
+		}
+
+		edges, err := expand(cur.frontier, forward)
+		if err != nil {
+			return nil, err
+		}
+		cur.depth++
+
+		var next []int
+		meet, best := -1, 0
+		for _, n := range cur.frontier {
+			for _, nb := range edges[n] {
+				if _, seen := cur.parent[nb]; seen {
+					continue
+				}
+				cur.parent[nb] = n
+				cur.dist[nb] = cur.depth
+				next = append(next, nb)
+				if d, ok := other.dist[nb]; ok && (meet == -1 || cur.depth+d < best) {
+					meet, best = nb, cur.depth+d
+				}
+			}
+		}
+		cur.frontier = next
+
+		if meet != -1 {
+			var path []int
+			for n := meet; n != from; n = fwd.parent[n] {
+				path = append(path, n)
+			}
+			path = append(path, from)
+			for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+				path[i], path[j] = path[j], path[i]
+			}
+			for n := meet; n != to; {
+				n = bwd.parent[n]
+				path = append(path, n)
+			}
+			return path, nil
+		}
+	}
+	return nil, ErrNoPath
+}
@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// seedGraph mirrors the user_friends seed in migrations/seed.sql plus a chain 8-9-10-11.
+func seedGraph() expandFunc {
+	pairs := [][2]int{
+		{1, 3}, {1, 4}, {1, 5}, {2, 3}, {2, 4}, {2, 5}, {1, 2},
+		{6, 7}, {8, 9}, {9, 10}, {10, 11}, {12, 13}, {14, 15},
+	}
+	adj := map[int][]int{}
+	for _, p := range pairs {
+		adj[p[0]] = append(adj[p[0]], p[1])
+		adj[p[1]] = append(adj[p[1]], p[0])
+	}
+	return func(frontier []int, _ bool) (map[int][]int, error) {
+		out := map[int][]int{}
+		for _, n := range frontier {
+			out[n] = adj[n]
+		}
+		return out, nil
+	}
+}
+
+func TestShortestPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to int
+		maxDepth int
+		want     []int
+		wantErr  error
+	}{
+		{"same user", 4, 4, 6, []int{4}, nil},
+		{"direct friends", 1, 2, 6, []int{1, 2}, nil},
+		{"two hops", 3, 4, 6, []int{3, 1, 4}, nil},
+		{"three hops", 8, 11, 6, []int{8, 9, 10, 11}, nil},
+		{"beyond max depth", 8, 11, 2, nil, ErrNoPath},
+		{"disconnected", 1, 20, 6, nil, ErrNoPath},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := shortestPath(tt.from, tt.to, tt.maxDepth, seedGraph())
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v; want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("path = %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
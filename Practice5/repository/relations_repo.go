@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/lib/pq"
+
+	"practice5/models"
+)
+
+// loadRelations fills in the relations inc asks for on every user of a page
+// with a single aggregated query. Soft-deleted friends are neither counted
+// nor embedded.
+func (r *Repository) loadRelations(ctx context.Context, users []models.User, inc models.Include) error {
+	if len(users) == 0 || (!inc.FriendCount && inc.Friends == 0) {
+		return nil
+	}
+	ids := make([]int, len(users))
+	for i, u := range users {
+		ids[i] = u.ID
+	}
+
+	// row_number() caps the embedded friends per user; the count still sees
+	// every friendship.
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT user_id,
+		       COUNT(*),
+		       COALESCE(json_agg(json_build_object(
+		           'id', id, 'name', name, 'email', email, 'gender', gender, 'birthdate', birthdate
+		       ) ORDER BY id) FILTER (WHERE rn <= $2), '[]')
+		FROM (
+			SELECT uf.user_id, u.id, u.name, u.email, u.gender, u.birthdate,
+			       row_number() OVER (PARTITION BY uf.user_id ORDER BY u.id) AS rn
+			FROM user_friends uf
+			JOIN users u ON u.id = uf.friend_id AND u.deleted_at IS NULL
+			WHERE uf.user_id = ANY($1)
+		) f
+		GROUP BY user_id`, pq.Array(ids), inc.Friends)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	type friendRow struct {
+		ID        int    `json:"id"`
+		Name      string `json:"name"`
+		Email     string `json:"email"`
+		Gender    string `json:"gender"`
+		Birthdate string `json:"birthdate"`
+	}
+	counts := map[int]int{}
+	friends := map[int][]models.User{}
+	for rows.Next() {
+		var id, count int
+		var raw []byte
+		if err := rows.Scan(&id, &count, &raw); err != nil {
+			return err
+		}
+		var fs []friendRow
+		if err := json.Unmarshal(raw, &fs); err != nil {
+			return err
+		}
+		for _, f := range fs {
+			bd, err := time.Parse("2006-01-02", f.Birthdate)
+			if err != nil {
+				return err
+			}
+			friends[id] = append(friends[id], models.User{ID: f.ID, Name: f.Name, Email: f.Email, Gender: f.Gender, Birthdate: bd})
+		}
+		counts[id] = count
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for i := range users {
+		attachRelations(&users[i], inc, counts[users[i].ID], friends[users[i].ID])
+	}
+	return nil
+}
+
+// attachRelations sets the requested relations on u; both stores share it
+// so users without friends look the same everywhere.
+func attachRelations(u *models.User, inc models.Include, count int, friends []models.User) {
+	if inc.FriendCount {
+		u.FriendCount = &count
+	}
+	if inc.Friends > 0 {
+		if friends == nil {
+			friends = []models.User{}
+		}
+		u.Friends = friends
+	}
+}
+
+// FriendLists returns the friends of each of userIDs, lowest id first, in a
+// single query. Soft-deleted friends are left out, and users without
+// friends are missing from the map.
+func (r *Repository) FriendLists(ctx context.Context, userIDs []int) (_ map[int][]models.User, err error) {
+	defer classify(ctx, &err)
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT uf.user_id, u.id, u.name, u.email, u.gender, u.birthdate
+		FROM user_friends uf
+		JOIN users u ON u.id = uf.friend_id AND u.deleted_at IS NULL
+		WHERE uf.user_id = ANY($1)
+		ORDER BY uf.user_id, u.id`, pq.Array(userIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lists := make(map[int][]models.User, len(userIDs))
+	for rows.Next() {
+		var owner int
+		var u models.User
+		if err := rows.Scan(&owner, &u.ID, &u.Name, &u.Email, &u.Gender, &u.Birthdate); err != nil {
+			return nil, err
+		}
+		lists[owner] = append(lists[owner], u)
+	}
+	return lists, rows.Err()
+}
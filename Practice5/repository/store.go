@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"practice5/models"
+)
+
+// UserStore is everything the handlers need from storage. Repository
+// implements it on Postgres, MemoryStore in process for tests and demos.
+// ctx bounds every call; once it is done, pending queries are cancelled.
+type UserStore interface {
+	GetPaginatedUsers(ctx context.Context, p models.FilterParams) (models.PaginatedResponse, error)
+	GetCommonFriends(ctx context.Context, userID1, userID2 int, includeDeleted bool) ([]models.User, error)
+	GetUserByID(ctx context.Context, id int) (models.User, error)
+	CreateUser(ctx context.Context, in models.UserInput) (models.User, error)
+	UpdateUser(ctx context.Context, id int, in models.UserInput) (models.User, error)
+	// DeleteUser soft-deletes: GetUserByID, UpdateUser and the user
+	// listings stop seeing the user (listings still do with IncludeDeleted)
+	// until RestoreUser brings it back or PurgeDeletedUsers removes it.
+	// Friendships are kept meanwhile.
+	DeleteUser(ctx context.Context, id int) error
+	RestoreUser(ctx context.Context, id int) (models.User, error)
+	// PurgeDeletedUsers hard-deletes users soft-deleted before cutoff and
+	// returns their ids.
+	PurgeDeletedUsers(ctx context.Context, cutoff time.Time) ([]int, error)
+
+	GetFriends(ctx context.Context, userID int, p models.FilterParams) (models.PaginatedResponse, error)
+	// FriendLists returns every friend of each user in one round trip, for
+	// callers that walk the graph level by level. Like every method built
+	// on friendships, it leaves soft-deleted users out.
+	FriendLists(ctx context.Context, userIDs []int) (map[int][]models.User, error)
+	GetFriendRequests(ctx context.Context, userID int, outgoing bool) ([]models.FriendRequest, error)
+	SendFriendRequest(ctx context.Context, from, to int) (models.FriendRequest, error)
+	AcceptFriendRequest(ctx context.Context, from, to int) error
+	DeclineFriendRequest(ctx context.Context, from, to int) error
+	CancelFriendRequest(ctx context.Context, from, to int) error
+	Unfriend(ctx context.Context, userID, friendID int) error
+	GetFriendSuggestions(ctx context.Context, userID, limit, sampleSize int) ([]models.FriendSuggestion, error)
+	FindFriendPath(ctx context.Context, from, to, maxDepth int) (models.FriendPath, error)
+	FriendGraph(ctx context.Context) (models.FriendGraph, error)
+
+	ExportUsers(ctx context.Context, p models.FilterParams, fn func(batch []models.User) error) error
+	ImportUsers(ctx context.Context, rows []models.ImportRow, allOrNothing bool) (int, []models.ImportError, error)
+
+	// DataVersion changes whenever users or friendships change, so it can
+	// stand in for the data behind any listing in an ETag.
+	DataVersion(ctx context.Context) (int64, error)
+}
+
+var (
+	_ UserStore = (*Repository)(nil)
+	_ UserStore = (*MemoryStore)(nil)
+	_ UserStore = (*instrumentedStore)(nil)
+	_ UserStore = (*cachedStore)(nil)
+)
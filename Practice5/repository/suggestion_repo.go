@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"github.com/lib/pq"
+
+	"practice5/models"
+)
+
+// GetFriendSuggestions ranks friends of friends of userID by the number of
+// mutual friends. Like GetCommonFriends it is a single query: the two hops
+// over user_friends are joined and grouped, and the mutual friends' names
+// are aggregated into an array instead of being looked up per candidate.
+func (r *Repository) GetFriendSuggestions(ctx context.Context, userID, limit, sampleSize int) (_ []models.FriendSuggestion, err error) {
+	defer classify(ctx, &err)
+	if _, err := r.GetUserByID(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT u.id, u.name, u.email, u.gender, u.birthdate,
+		       COUNT(*)                                              AS mutual_count,
+		       (ARRAY_AGG(m.name ORDER BY m.name COLLATE "C"))[1:$3] AS mutual_friends
+		FROM user_friends uf1
+		JOIN user_friends uf2 ON uf2.user_id = uf1.friend_id
+		JOIN users u          ON u.id = uf2.friend_id AND u.deleted_at IS NULL
+		JOIN users m          ON m.id = uf1.friend_id AND m.deleted_at IS NULL
+		WHERE uf1.user_id = $1
+		  AND uf2.friend_id <> $1
+		  AND NOT EXISTS (
+		      SELECT 1 FROM user_friends f
+		      WHERE f.user_id = $1 AND f.friend_id = uf2.friend_id
+		  )
+		GROUP BY u.id
+		ORDER BY mutual_count DESC, u.id
+		LIMIT $2
+	`
+	rows, err := r.db.QueryContext(ctx, query, userID, limit, sampleSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	suggestions := []models.FriendSuggestion{}
+	for rows.Next() {
+		var s models.FriendSuggestion
+		if err := rows.Scan(
+			&s.ID, &s.Name, &s.Email, &s.Gender, &s.Birthdate,
+			&s.MutualCount, pq.Array(&s.MutualFriends),
+		); err != nil {
+			return nil, err
+		}
+		suggestions = append(suggestions, s)
+	}
+	return suggestions, rows.Err()
+}
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"strings"
@@ -24,97 +25,255 @@ var allowedColumns = map[string]bool{
 	"birthdate": true,
 }
 
-func (r *Repository) GetPaginatedUsers(p models.FilterParams) (models.PaginatedResponse, error) {
-	args := []interface{}{}
-	argIdx := 1
-	whereClauses := []string{}
+var (
+	ErrInvalidSort   = &Error{Kind: KindInvalid, Code: "invalid_sort", Message: "invalid sort", Field: "sort"}
+	ErrInvalidFields = &Error{Kind: KindInvalid, Code: "invalid_fields", Message: "invalid fields", Field: "fields"}
+)
+
+// sortKeys resolves ?sort=gender,-birthdate,name (a leading "-" means
+// descending) or, when sort is absent, the older order_by/order_dir pair.
+// id is always appended as the final tiebreaker so that every row has a
+// unique position and pages never overlap or skip rows.
+func sortKeys(p models.FilterParams) ([]sortKey, error) {
+	var keys []sortKey
+	seen := map[string]bool{}
+
+	if p.Sort != "" {
+		for _, part := range strings.Split(p.Sort, ",") {
+			part = strings.TrimSpace(part)
+			k := sortKey{col: strings.TrimLeft(part, "+-"), desc: strings.HasPrefix(part, "-")}
+			if !allowedColumns[k.col] {
+				return nil, detail(ErrInvalidSort, "sort", "unknown column %q, allowed: id, name, email, gender, birthdate", k.col)
+			}
+			if seen[k.col] {
+				return nil, detail(ErrInvalidSort, "sort", "column %q listed twice", k.col)
+			}
+			seen[k.col] = true
+			keys = append(keys, k)
+		}
+	} else {
+		orderCol := "id" // default
+		if p.OrderBy != "" {
+			if !allowedColumns[p.OrderBy] {
+				return nil, detail(ErrInvalidSort, "order_by", "unknown column %q, allowed: id, name, email, gender, birthdate", p.OrderBy)
+			}
+			orderCol = p.OrderBy
+		}
+		dir := strings.ToUpper(p.OrderDir)
+		if dir != "" && dir != "ASC" && dir != "DESC" {
+			return nil, detail(ErrInvalidSort, "order_dir", "must be ASC or DESC, got %q", p.OrderDir)
+		}
+		keys = append(keys, sortKey{col: orderCol, desc: dir == "DESC"})
+		seen[orderCol] = true
+	}
+
+	if !seen["id"] {
+		keys = append(keys, sortKey{col: "id"})
+	}
+	return keys, nil
+}
 
-	if p.ID != nil {
-		whereClauses = append(whereClauses, fmt.Sprintf("id = $%d", argIdx))
-		args = append(args, *p.ID)
-		argIdx++
+// checkParams validates the filters and fields of p and resolves its sort keys.
+func checkParams(p models.FilterParams) ([]sortKey, error) {
+	if err := checkFilter(p.Expr()); err != nil {
+		return nil, err
 	}
-	if p.Name != nil {
-		whereClauses = append(whereClauses, fmt.Sprintf("name ILIKE $%d", argIdx))
-		args = append(args, "%"+*p.Name+"%")
-		argIdx++
+	for _, f := range p.Fields {
+		if !allowedColumns[f] {
+			return nil, detail(ErrInvalidFields, "fields", "unknown field %q, allowed: id, name, email, gender, birthdate", f)
+		}
 	}
-	if p.Email != nil {
-		whereClauses = append(whereClauses, fmt.Sprintf("email ILIKE $%d", argIdx))
-		args = append(args, "%"+*p.Email+"%")
-		argIdx++
+	return sortKeys(p)
+}
+
+// ValidateParams reports the error GetPaginatedUsers would return for p's
+// filter, fields or sort, without running a query. Handlers call it before
+// answering a conditional request, so bad params never get a 304.
+func ValidateParams(p models.FilterParams) error {
+	_, err := checkParams(p)
+	return err
+}
+
+// userColumns is the users select list in table order.
+var userColumns = []string{"id", "name", "email", "gender", "birthdate", "deleted_at"}
+
+// selectList returns the columns to fetch for fields (nil means all). id and
+// the sort columns always come along: cursors and tiebreaks need them.
+func selectList(fields []string, keys []sortKey) string {
+	if fields == nil {
+		return strings.Join(userColumns, ", ")
 	}
-	if p.Gender != nil {
-		whereClauses = append(whereClauses, fmt.Sprintf("gender = $%d", argIdx))
-		args = append(args, *p.Gender)
-		argIdx++
+	want := map[string]bool{"id": true}
+	for _, f := range fields {
+		want[f] = true
 	}
-	if p.Birthdate != nil {
-		whereClauses = append(whereClauses, fmt.Sprintf("birthdate = $%d", argIdx))
-		args = append(args, *p.Birthdate)
-		argIdx++
+	for _, k := range keys {
+		want[k.col] = true
 	}
+	var cols []string
+	for _, c := range userColumns {
+		if want[c] {
+			cols = append(cols, c)
+		}
+	}
+	return strings.Join(cols, ", ")
+}
 
-	where := ""
-	if len(whereClauses) > 0 {
-		where = "WHERE " + strings.Join(whereClauses, " AND ")
+func (r *Repository) GetPaginatedUsers(ctx context.Context, p models.FilterParams) (_ models.PaginatedResponse, err error) {
+	defer classify(ctx, &err)
+	whereClauses, args := buildFilters(p)
+	argIdx := len(args) + 1
+
+	keys, err := checkParams(p)
+	if err != nil {
+		return models.PaginatedResponse{}, err
 	}
 
-	orderCol := "id" // default
-	if p.OrderBy != "" && allowedColumns[p.OrderBy] {
-		orderCol = p.OrderBy
+	if p.Cursor != nil {
+		res, err := r.getUsersByCursor(ctx, whereClauses, args, keys, selectList(p.Fields, keys), *p.Cursor, p.PageSize)
+		if err != nil {
+			return res, err
+		}
+		return res, r.loadRelations(ctx, res.Data, p.Include)
 	}
-	orderDir := "ASC" // default
-	if strings.ToUpper(p.OrderDir) == "DESC" {
-		orderDir = "DESC"
+
+	where := ""
+	if len(whereClauses) > 0 {
+		where = "WHERE " + strings.Join(whereClauses, " AND ")
 	}
 
 	countQuery := fmt.Sprintf(`SELECT COUNT(*) FROM users %s`, where)
 	var total int
-	if err := r.db.QueryRow(countQuery, args...).Scan(&total); err != nil {
+	if err := r.db.QueryRowContext(ctx, countQuery, args...).Scan(&total); err != nil {
 		return models.PaginatedResponse{}, err
 	}
 
-	offset := (p.Page - 1) * p.PageSize
+	offset, ok := pageOffset(p.Page, p.PageSize, total)
+	if !ok {
+		return offsetPage(nil, total, p.Page, p.PageSize), nil
+	}
 	dataQuery := fmt.Sprintf(
-		`SELECT id, name, email, gender, birthdate FROM users %s ORDER BY %s %s LIMIT $%d OFFSET $%d`,
-		where, orderCol, orderDir, argIdx, argIdx+1,
+		`SELECT %s FROM users %s ORDER BY %s LIMIT $%d OFFSET $%d`,
+		selectList(p.Fields, keys), where, orderByClause(keys, false), argIdx, argIdx+1,
 	)
 	dataArgs := append(args, p.PageSize, offset)
 
-	rows, err := r.db.Query(dataQuery, dataArgs...)
+	users, err := r.queryUsers(ctx, dataQuery, dataArgs...)
+	if err != nil {
+		return models.PaginatedResponse{}, err
+	}
+	if err := r.loadRelations(ctx, users, p.Include); err != nil {
+		return models.PaginatedResponse{}, err
+	}
+
+	return offsetPage(users, total, p.Page, p.PageSize), nil
+}
+
+// getUsersByCursor serves one page in keyset mode. It never runs COUNT(*);
+// instead it fetches one extra row to find out whether another page exists.
+// An empty cursor string selects the first page.
+func (r *Repository) getUsersByCursor(ctx context.Context, whereClauses []string, args []interface{}, keys []sortKey, columns, rawCursor string, pageSize int) (models.PaginatedResponse, error) {
+	var c cursor
+	if rawCursor != "" {
+		var err error
+		if c, err = decodeCursor(rawCursor, keys); err != nil {
+			return models.PaginatedResponse{}, err
+		}
+		clause, keyArgs := keysetClause(keys, c.Values, c.Backward, len(args)+1)
+		whereClauses = append(whereClauses, clause)
+		args = append(args, keyArgs...)
+	}
+
+	where := ""
+	if len(whereClauses) > 0 {
+		where = "WHERE " + strings.Join(whereClauses, " AND ")
+	}
+	dataQuery := fmt.Sprintf(
+		`SELECT %s FROM users %s ORDER BY %s LIMIT $%d`,
+		columns, where, orderByClause(keys, c.Backward), len(args)+1,
+	)
+	users, err := r.queryUsers(ctx, dataQuery, append(args, pageSize+1)...)
 	if err != nil {
 		return models.PaginatedResponse{}, err
 	}
+	return cursorPage(users, keys, c, rawCursor != "", pageSize), nil
+}
+
+func buildFilters(p models.FilterParams) ([]string, []interface{}) {
+	whereClauses := []string{}
+	clause, args := compileFilter(p.Expr(), 1)
+	if clause != "" {
+		whereClauses = append(whereClauses, clause)
+	}
+	if p.FriendsOf != nil {
+		whereClauses = append(whereClauses, fmt.Sprintf("id IN (SELECT friend_id FROM user_friends WHERE user_id = $%d)", len(args)+1))
+		args = append(args, *p.FriendsOf)
+	}
+	if !p.IncludeDeleted {
+		whereClauses = append(whereClauses, "deleted_at IS NULL")
+	}
+	return whereClauses, args
+}
+
+// queryUsers runs a query selecting any subset of the users columns, as
+// chosen by selectList.
+func (r *Repository) queryUsers(ctx context.Context, query string, args ...interface{}) ([]models.User, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
 	defer rows.Close()
 
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
 	var users []models.User
 	for rows.Next() {
 		var u models.User
-		if err := rows.Scan(&u.ID, &u.Name, &u.Email, &u.Gender, &u.Birthdate); err != nil {
-			return models.PaginatedResponse{}, err
+		dest := make([]interface{}, len(cols))
+		for i, c := range cols {
+			dest[i] = userField(&u, c)
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
 		}
 		users = append(users, u)
 	}
+	return users, rows.Err()
+}
 
-	return models.PaginatedResponse{
-		Data:       users,
-		TotalCount: total,
-		Page:       p.Page,
-		PageSize:   p.PageSize,
-	}, nil
+func userField(u *models.User, col string) interface{} {
+	switch col {
+	case "id":
+		return &u.ID
+	case "name":
+		return &u.Name
+	case "email":
+		return &u.Email
+	case "gender":
+		return &u.Gender
+	case "birthdate":
+		return &u.Birthdate
+	case "deleted_at":
+		return &u.DeletedAt
+	}
+	panic("repository: unexpected users column " + col)
 }
 
-func (r *Repository) GetCommonFriends(userID1, userID2 int) ([]models.User, error) {
+// GetCommonFriends skips soft-deleted friends unless includeDeleted is set.
+func (r *Repository) GetCommonFriends(ctx context.Context, userID1, userID2 int, includeDeleted bool) (_ []models.User, err error) {
+	defer classify(ctx, &err)
 	query := `
-		SELECT u.id, u.name, u.email, u.gender, u.birthdate
+		SELECT u.id, u.name, u.email, u.gender, u.birthdate, u.deleted_at
 		FROM user_friends uf1
 		JOIN user_friends uf2 ON uf1.friend_id = uf2.friend_id
 		JOIN users u          ON u.id = uf1.friend_id
 		WHERE uf1.user_id = $1
 		  AND uf2.user_id = $2
+		  AND ($3 OR u.deleted_at IS NULL)
 	`
-	rows, err := r.db.Query(query, userID1, userID2)
+	rows, err := r.db.QueryContext(ctx, query, userID1, userID2, includeDeleted)
 	if err != nil {
 		return nil, err
 	}
@@ -123,10 +282,10 @@ func (r *Repository) GetCommonFriends(userID1, userID2 int) ([]models.User, erro
 	var users []models.User
 	for rows.Next() {
 		var u models.User
-		if err := rows.Scan(&u.ID, &u.Name, &u.Email, &u.Gender, &u.Birthdate); err != nil {
+		if err := rows.Scan(&u.ID, &u.Name, &u.Email, &u.Gender, &u.Birthdate, &u.DeletedAt); err != nil {
 			return nil, err
 		}
 		users = append(users, u)
 	}
-	return users, nil
+	return users, rows.Err()
 }
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"practice5/models"
+)
+
+func TestSortKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		p    models.FilterParams
+		want []sortKey
+	}{
+		{"default", models.FilterParams{}, []sortKey{{col: "id"}}},
+		{"order_by", models.FilterParams{OrderBy: "name", OrderDir: "desc"},
+			[]sortKey{{col: "name", desc: true}, {col: "id"}}},
+		{"order_dir alone", models.FilterParams{OrderDir: "Desc"}, []sortKey{{col: "id", desc: true}}},
+		{"multi-column", models.FilterParams{Sort: "gender,-birthdate, name", OrderBy: "email"},
+			[]sortKey{{col: "gender"}, {col: "birthdate", desc: true}, {col: "name"}, {col: "id"}}},
+		{"explicit id", models.FilterParams{Sort: "-id"}, []sortKey{{col: "id", desc: true}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := sortKeys(tt.p)
+			if err != nil {
+				t.Fatalf("sortKeys: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v; want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSortKeysRejects(t *testing.T) {
+	for _, sort := range []string{"salary", "name,-name", "name,"} {
+		if _, err := sortKeys(models.FilterParams{Sort: sort}); !errors.Is(err, ErrInvalidSort) {
+			t.Errorf("sortKeys(%q) error = %v; want ErrInvalidSort", sort, err)
+		}
+	}
+	for _, p := range []models.FilterParams{{OrderBy: "salary"}, {OrderBy: "name", OrderDir: "down"}} {
+		if _, err := sortKeys(p); !errors.Is(err, ErrInvalidSort) {
+			t.Errorf("sortKeys(%+v) error = %v; want ErrInvalidSort", p, err)
+		}
+	}
+}
+
+func TestOrderByClause(t *testing.T) {
+	keys := []sortKey{{col: "gender"}, {col: "birthdate", desc: true}, {col: "id"}}
+	if got, want := orderByClause(keys, false), `gender COLLATE "C" ASC, birthdate DESC, id ASC`; got != want {
+		t.Errorf("forward = %q; want %q", got, want)
+	}
+	if got, want := orderByClause(keys, true), `gender COLLATE "C" DESC, birthdate ASC, id DESC`; got != want {
+		t.Errorf("backward = %q; want %q", got, want)
+	}
+}
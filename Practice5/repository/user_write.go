@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+
+	"practice5/models"
+)
+
+var (
+	ErrNotFound   = &Error{Kind: KindNotFound, Code: "user_not_found", Message: "user not found"}
+	ErrEmailTaken = &Error{Kind: KindConflict, Code: "email_taken", Message: "email already in use", Field: "email"}
+)
+
+const uniqueViolation = "23505"
+
+func (r *Repository) GetUserByID(ctx context.Context, id int) (_ models.User, err error) {
+	defer classify(ctx, &err)
+	var u models.User
+	err = r.db.QueryRowContext(ctx,
+		`SELECT id, name, email, gender, birthdate FROM users WHERE id = $1 AND deleted_at IS NULL`, id,
+	).Scan(&u.ID, &u.Name, &u.Email, &u.Gender, &u.Birthdate)
+	if errors.Is(err, sql.ErrNoRows) {
+		return u, ErrNotFound
+	}
+	return u, err
+}
+
+func (r *Repository) CreateUser(ctx context.Context, in models.UserInput) (_ models.User, err error) {
+	defer classify(ctx, &err)
+	var u models.User
+	err = r.db.QueryRowContext(ctx, `
+		INSERT INTO users (name, email, gender, birthdate)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, name, email, gender, birthdate`,
+		in.Name, in.Email, in.Gender, in.Birthdate,
+	).Scan(&u.ID, &u.Name, &u.Email, &u.Gender, &u.Birthdate)
+	return u, mapWriteErr(err)
+}
+
+// UpdateUser overwrites the fields set in in and leaves nil fields untouched,
+// so it serves both PUT (every field set) and PATCH. Soft-deleted users are
+// not found.
+func (r *Repository) UpdateUser(ctx context.Context, id int, in models.UserInput) (_ models.User, err error) {
+	defer classify(ctx, &err)
+	var u models.User
+	err = r.db.QueryRowContext(ctx, `
+		UPDATE users SET
+			name      = COALESCE($2::text, name),
+			email     = COALESCE($3::text, email),
+			gender    = COALESCE($4::text, gender),
+			birthdate = COALESCE($5::date, birthdate)
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING id, name, email, gender, birthdate`,
+		id, in.Name, in.Email, in.Gender, in.Birthdate,
+	).Scan(&u.ID, &u.Name, &u.Email, &u.Gender, &u.Birthdate)
+	if errors.Is(err, sql.ErrNoRows) {
+		return u, ErrNotFound
+	}
+	return u, mapWriteErr(err)
+}
+
+// DeleteUser soft-deletes the user: it disappears from reads but keeps its
+// email and user_friends rows until RestoreUser or PurgeDeletedUsers.
+func (r *Repository) DeleteUser(ctx context.Context, id int) (err error) {
+	defer classify(ctx, &err)
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE users SET deleted_at = now() WHERE id = $1 AND deleted_at IS NULL`, id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// RestoreUser undoes DeleteUser, friendships included. Restoring a user
+// that is not deleted is a no-op.
+func (r *Repository) RestoreUser(ctx context.Context, id int) (_ models.User, err error) {
+	defer classify(ctx, &err)
+	var u models.User
+	err = r.db.QueryRowContext(ctx, `
+		UPDATE users SET deleted_at = NULL
+		WHERE id = $1
+		RETURNING id, name, email, gender, birthdate`, id,
+	).Scan(&u.ID, &u.Name, &u.Email, &u.Gender, &u.Birthdate)
+	if errors.Is(err, sql.ErrNoRows) {
+		return u, ErrNotFound
+	}
+	return u, err
+}
+
+// PurgeDeletedUsers hard-deletes users soft-deleted before cutoff and
+// returns their ids; ON DELETE CASCADE removes their friendships and
+// friend requests.
+func (r *Repository) PurgeDeletedUsers(ctx context.Context, cutoff time.Time) (_ []int, err error) {
+	defer classify(ctx, &err)
+	rows, err := r.db.QueryContext(ctx,
+		`DELETE FROM users WHERE deleted_at < $1 RETURNING id`, cutoff)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func mapWriteErr(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+		return ErrEmailTaken
+	}
+	return err
+}
@@ -0,0 +1,16 @@
+package repository
+
+import "context"
+
+// DataVersion sums the counters that the bump_table_version triggers keep
+// for users and user_friends, spread over per-connection slots so writers
+// do not queue on one row. They only grow, so the sum changes with any
+// committed write to either table.
+func (r *Repository) DataVersion(ctx context.Context) (v int64, err error) {
+	defer classify(ctx, &err)
+	err = r.db.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(version), 0)::bigint FROM table_versions
+		WHERE table_name IN ('users', 'user_friends')`,
+	).Scan(&v)
+	return v, err
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"practice5/handler"
+)
+
+// Server-wide limits. Routes with a longer query budget extend their own
+// read and write deadlines, see handler.WithTimeout.
+const (
+	readHeaderTimeout = 5 * time.Second
+	readTimeout       = 30 * time.Second
+	writeTimeout      = 30 * time.Second
+	idleTimeout       = 2 * time.Minute
+)
+
+// serve runs srv until SIGINT or SIGTERM, then fails readiness, waits
+// drainDelay for the load balancer to notice, and lets in-flight requests
+// finish for up to shutdownTimeout before closing what is left.
+func serve(srv *http.Server, health *handler.Health, drainDelay, shutdownTimeout time.Duration) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	errc := make(chan error, 1)
+	go func() { errc <- srv.ListenAndServe() }()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+	stop() // a second signal kills the process the usual way
+
+	log.Println("🛑 Shutting down: draining requests")
+	health.Drain()
+	time.Sleep(drainDelay)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Println("⚠️  Drain timed out, closing remaining connections:", err)
+		srv.Close()
+	}
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	log.Println("👋 Server stopped")
+	return nil
+}